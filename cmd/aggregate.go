@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// aggregateFuncs are the names of the supported aggregation functions
+var aggregateFuncs = []string{"count", "sum", "min", "max", "mean"}
+
+// Aggregate is an aggregation function over the numeric values of the fields
+// selected by a range, like "sum:5". The "count" function counts records and
+// takes no range.
+type Aggregate struct {
+	Func   string
+	Spec   string
+	ranges Ranges
+}
+
+// ParseAggregate parses a string like "sum:5" or "count" into an Aggregate
+func ParseAggregate(str string) (*Aggregate, error) {
+	fn, spec, _ := strings.Cut(str, ":")
+	if !slices.Contains(aggregateFuncs, fn) {
+		return nil, fmt.Errorf("unknown aggregate function: %q", fn)
+	}
+	if fn == "count" && spec != "" {
+		return nil, fmt.Errorf("count does not take a range: %q", str)
+	}
+	if fn != "count" && spec == "" {
+		return nil, fmt.Errorf("%s requires a range: %q", fn, str)
+	}
+	return &Aggregate{Func: fn, Spec: spec}, nil
+}
+
+// Name returns the name of the aggregate, which is used as the column name
+func (a *Aggregate) Name() string {
+	if a.Spec == "" {
+		return a.Func
+	}
+	return a.Func + ":" + a.Spec
+}
+
+// Bind resolves the range of the aggregate with column names from header,
+// which may be nil.
+func (a *Aggregate) Bind(header []string) error {
+	if a.Spec == "" {
+		return nil
+	}
+	r, err := parseRange(a.Spec, header)
+	if err != nil {
+		return err
+	}
+	a.ranges = Ranges{r}
+	return nil
+}
+
+// aggregateState is the accumulated state of an Aggregate
+type aggregateState struct {
+	records  int
+	values   int
+	invalid  int
+	sum      float64
+	min, max float64
+}
+
+// add adds the values selected by a from the fields of a record
+func (s *aggregateState) add(a *Aggregate, fields []string) {
+	s.records++
+	if a.ranges == nil {
+		return
+	}
+
+	for str := range slices.Values(a.ranges.Select(fields)) {
+		v, ok := parseDecimal(str)
+		if !ok {
+			s.invalid++
+			continue
+		}
+		if s.values == 0 {
+			s.min, s.max = v, v
+		}
+		s.values++
+		s.sum += v
+		s.min = min(s.min, v)
+		s.max = max(s.max, v)
+	}
+}
+
+// result returns the value of the aggregation function fn. It returns an empty
+// string if there are no values to aggregate.
+func (s *aggregateState) result(fn string) string {
+	if fn == "count" {
+		return strconv.Itoa(s.records)
+	}
+	if s.values == 0 {
+		return ""
+	}
+
+	var v float64
+	switch fn {
+	case "sum":
+		v = s.sum
+	case "min":
+		v = s.min
+	case "max":
+		v = s.max
+	case "mean":
+		v = s.sum / float64(s.values)
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// aggregateGroup is the state of the aggregates of the records with the same
+// key
+type aggregateGroup struct {
+	key    []string
+	states []aggregateState
+}
+
+// Aggregator accumulates aggregates over all records of the input, or over the
+// records of each group with the same key when grouped. Groups are kept in the
+// order their keys first appear.
+type Aggregator struct {
+	aggregates []*Aggregate
+	groupSpecs []string
+	groupBy    Ranges
+	header     []string
+	groups     map[string]*aggregateGroup
+	order      []*aggregateGroup
+}
+
+// NewAggregator returns an Aggregator for the aggregate specs. Records are
+// grouped by the fields selected by the ranges of groupBy if it is not empty.
+func NewAggregator(specs []string, groupBy []string) (*Aggregator, error) {
+	aggregates := make([]*Aggregate, len(specs))
+	for i, spec := range specs {
+		a, err := ParseAggregate(spec)
+		if err != nil {
+			return nil, err
+		}
+		aggregates[i] = a
+	}
+	return &Aggregator{
+		aggregates: aggregates,
+		groupSpecs: groupBy,
+		groups:     map[string]*aggregateGroup{},
+	}, nil
+}
+
+// Bind resolves the ranges of the aggregates and the group keys with column
+// names from header, which may be nil.
+func (ag *Aggregator) Bind(header []string) error {
+	for a := range slices.Values(ag.aggregates) {
+		if err := a.Bind(header); err != nil {
+			return err
+		}
+	}
+	ranges, err := parseRanges(ag.groupSpecs, header)
+	if err != nil {
+		return err
+	}
+	ag.groupBy, ag.header = ranges, header
+	return nil
+}
+
+// Add adds the fields of a record to the aggregates of its group
+func (ag *Aggregator) Add(fields []string) {
+	key := ag.groupBy.Select(fields)
+	id := selectionKey(key)
+
+	g, ok := ag.groups[id]
+	if !ok {
+		g = &aggregateGroup{
+			key:    slices.Clone(key),
+			states: make([]aggregateState, len(ag.aggregates)),
+		}
+		ag.groups[id] = g
+		ag.order = append(ag.order, g)
+	}
+
+	for i, a := range ag.aggregates {
+		g.states[i].add(a, fields)
+	}
+}
+
+// Names returns the names of the group key columns followed by the names of
+// the aggregates
+func (ag *Aggregator) Names() []string {
+	names := ag.groupBy.Select(ag.header)
+	if ag.header == nil {
+		names = slices.Clone(ag.groupSpecs)
+	}
+	for a := range slices.Values(ag.aggregates) {
+		names = append(names, a.Name())
+	}
+	return names
+}
+
+// Rows returns a row for each group with the fields of its key followed by the
+// results of the aggregates. There is a single row if the records are not
+// grouped. Values which are not numeric are skipped and reported as a warning.
+func (ag *Aggregator) Rows() [][]string {
+	if len(ag.groupSpecs) == 0 && len(ag.order) == 0 {
+		// an empty input still has a summary
+		ag.order = append(ag.order, &aggregateGroup{
+			states: make([]aggregateState, len(ag.aggregates)),
+		})
+	}
+
+	invalid := make([]int, len(ag.aggregates))
+	rows := make([][]string, len(ag.order))
+	for i, g := range ag.order {
+		row := slices.Clip(g.key)
+		for j, a := range ag.aggregates {
+			row = append(row, g.states[j].result(a.Func))
+			invalid[j] += g.states[j].invalid
+		}
+		rows[i] = row
+	}
+
+	for i, a := range ag.aggregates {
+		if invalid[i] > 0 {
+			slog.Warn("Skipped non-numeric values",
+				"aggregate", a.Name(), "count", invalid[i],
+			)
+		}
+	}
+	return rows
+}
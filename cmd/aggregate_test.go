@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestAggregator(t *testing.T) {
+	header := []string{"NAME", "SIZE", "MTIME"}
+	records := [][]string{
+		{"a.txt", "512", "1"},
+		{"b.txt", "2048", "3"},
+		{"c.txt", "4.0K", "2"},
+		{"d.txt", "-256", "5"},
+		{"e.txt"},
+		{"f.txt", "Infinity", "NaN"},
+	}
+
+	tests := []struct {
+		specs []string
+		want  []string
+	}{
+		{[]string{"count"}, []string{"6"}},
+		{[]string{"sum:2"}, []string{"2304"}},
+		{[]string{"sum:SIZE", "max:SIZE"}, []string{"2304", "2048"}},
+		{[]string{"min:2", "mean:3"}, []string{"-256", "2.75"}},
+		{[]string{"sum:2:3"}, []string{"2315"}},
+		{[]string{"max:1"}, []string{""}},
+		{[]string{"mean:MTIME", "count"}, []string{"2.75", "6"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.specs[0], func(t *testing.T) {
+			ag, err := NewAggregator(tt.specs, nil)
+			if err != nil {
+				t.Fatalf("NewAggregator(%q) failed: %v", tt.specs, err)
+			}
+			if err := ag.Bind(header); err != nil {
+				t.Fatalf("Bind() failed: %v", err)
+			}
+			for fields := range slices.Values(records) {
+				ag.Add(fields)
+			}
+			rows := ag.Rows()
+			if len(rows) != 1 || !slices.Equal(rows[0], tt.want) {
+				t.Errorf("Rows() = %q, want [%q]", rows, tt.want)
+			}
+		})
+	}
+}
+
+func TestAggregator_GroupBy(t *testing.T) {
+	header := []string{"USER", "PID", "RSS"}
+	records := [][]string{
+		{"root", "1", "1024"},
+		{"nadim", "2048", "512"},
+		{"root", "2", "256"},
+		{"nadim", "4096", "?"},
+		{"daemon", "100", "8"},
+	}
+
+	tests := []struct {
+		specs   []string
+		groupBy []string
+		names   []string
+		want    [][]string
+	}{
+		{
+			[]string{"count", "sum:RSS"},
+			[]string{"USER"},
+			[]string{"USER", "count", "sum:RSS"},
+			[][]string{
+				{"root", "2", "1280"},
+				{"nadim", "2", "512"},
+				{"daemon", "1", "8"},
+			},
+		},
+		{
+			[]string{"max:2"},
+			[]string{"1"},
+			[]string{"USER", "max:2"},
+			[][]string{{"root", "2"}, {"nadim", "4096"}, {"daemon", "100"}},
+		},
+		{
+			[]string{"count"},
+			[]string{"USER", "RSS"},
+			[]string{"USER", "RSS", "count"},
+			[][]string{
+				{"root", "1024", "1"},
+				{"nadim", "512", "1"},
+				{"root", "256", "1"},
+				{"nadim", "?", "1"},
+				{"daemon", "8", "1"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.specs[0], func(t *testing.T) {
+			ag, err := NewAggregator(tt.specs, tt.groupBy)
+			if err != nil {
+				t.Fatalf("NewAggregator(%q) failed: %v", tt.specs, err)
+			}
+			if err := ag.Bind(header); err != nil {
+				t.Fatalf("Bind() failed: %v", err)
+			}
+			for fields := range slices.Values(records) {
+				ag.Add(fields)
+			}
+			if got := ag.Names(); !slices.Equal(got, tt.names) {
+				t.Errorf("Names() = %q, want %q", got, tt.names)
+			}
+			got := ag.Rows()
+			if !slices.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("Rows() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAggregate_Invalid(t *testing.T) {
+	invalid := []string{"", "sum", "sum:", "count:1", "median:2", "total"}
+
+	for _, str := range invalid {
+		t.Run(str, func(t *testing.T) {
+			if _, err := ParseAggregate(str); err == nil {
+				t.Errorf("ParseAggregate(%q) succeeded, want error", str)
+			}
+		})
+	}
+}
+
+func TestAggregateValue(t *testing.T) {
+	var specs []string
+	var count bool
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.Var(&aggregateValue{"sum", &specs}, "sum", "")
+	flags.Var(&aggregateValue{"", &specs}, "agg", "")
+	flags.Var(&countValue{&count, &specs}, "count", "")
+	flags.Lookup("count").NoOptDefVal = "true"
+
+	args := []string{"--sum", "2", "--count", "--agg", "max:3,mean:2", "--sum", "1"}
+	if err := flags.Parse(args); err != nil {
+		t.Fatalf("Parse(%q) failed: %v", args, err)
+	}
+	want := []string{"sum:2", "count", "max:3", "mean:2", "sum:1"}
+	if !slices.Equal(specs, want) || !count {
+		t.Errorf("specs = %q, want %q", specs, want)
+	}
+
+	if err := flags.Parse([]string{"--count=false"}); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	want = []string{"sum:2", "max:3", "mean:2", "sum:1"}
+	if !slices.Equal(specs, want) || count {
+		t.Errorf("specs = %q, want %q", specs, want)
+	}
+}
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"bufio"
-	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -10,38 +9,206 @@ import (
 	"math"
 	"os"
 	"slices"
-	"unicode"
+	"strconv"
+	"strings"
 
 	"github.com/carapace-sh/carapace"
-	shlex "github.com/carapace-sh/carapace-shlex"
 	"github.com/charmbracelet/log"
 	"github.com/spf13/cobra"
-	"github.com/valyala/fasttemplate"
 )
 
 var (
-	delimiter   = "space"
-	format      = "none"
-	ignoreEmpty = false
-	shell       = false
+	delimiters     []string
+	regexDelimiter = ""
+	format         = "none"
+	formatDelims   = "{ }"
+	ignoreEmpty    = false
+	header         = false
+	complement     = false
+	byteChars      = false
+	widths         = ""
+	tableDetect    = false
+	keepEmpty      = false
+	nullData       = false
+	print0         = false
+	regexRecordSep = ""
+	paragraph      = false
+	recordStart    = ""
+	where          = ""
+	matches        []string
+	invertMatch    = false
+	shell          = false
+	csvInput       = false
+	tsvInput       = false
+	withFilename   = false
+	count          = false
+	aggregates     []string
+	unique         = false
+	uniqueAdjacent = false
+	groupBy        []string
+	output         = "plain"
+	files          []string
 )
 
 var limit limitValue = math.MaxInt
 
+var (
+	recordSep   escapedValue = "\n"
+	outputDelim escapedValue = " "
+	outputTerm  escapedValue = "\n"
+)
+
 func init() {
 	flags := Command.Flags()
 	flags.BoolVarP(&ignoreEmpty,
 		"ignore-empty", "i", ignoreEmpty, "ignore empty lines",
 	)
 	flags.BoolVarP(&shell, "shlex", "s", ignoreEmpty, "spilt qoute like shells")
-	flags.StringVarP(&delimiter,
-		"delimiter", "d", delimiter, "delimiter for field separation",
+	flags.StringArrayVarP(&delimiters,
+		"delimiter", "d", delimiters,
+		"delimiter for field separation, can be repeated or a set like [,;|]",
+	)
+	flags.StringVarP(&regexDelimiter,
+		"regex-delimiter", "r", regexDelimiter,
+		"regular expression for field separation",
+	)
+	flags.BoolVarP(&keepEmpty,
+		"keep-empty", "k", keepEmpty,
+		"keep empty fields between consecutive delimiters",
+	)
+	flags.StringVar(&widths,
+		"widths", widths, "comma separated column widths, '*' for the rest",
+	)
+	flags.BoolVar(&tableDetect,
+		"table-detect", tableDetect, "detect the columns of an aligned table",
+	)
+	flags.BoolVar(&csvInput, "csv", csvInput, "parse input as RFC 4180 CSV")
+	flags.BoolVar(&tsvInput,
+		"tsv", tsvInput, "parse input as tab separated values",
+	)
+	flags.BoolVar(&header,
+		"header", header, "use the first record as column names",
+	)
+	flags.BoolVar(&complement,
+		"complement", complement, "select all fields except the selected ones",
+	)
+	flags.BoolVar(&byteChars,
+		"bytes", byteChars, "slice characters of fields as bytes",
+	)
+	flags.StringVarP(&where,
+		"where", "w", where, "only print records matching the filter expression",
+	)
+	flags.StringArrayVarP(&matches,
+		"match", "m", matches,
+		"only print records where RANGE=REGEX matches, can be repeated",
+	)
+	flags.BoolVarP(&invertMatch,
+		"invert-match", "v", invertMatch,
+		"only print records which do not match",
+	)
+	flags.Var(&aggregateValue{"sum", &aggregates},
+		"sum", "print the sum of the values of a range at the end",
+	)
+	flags.Var(&aggregateValue{"min", &aggregates},
+		"min", "print the minimum of the values of a range at the end",
+	)
+	flags.Var(&aggregateValue{"max", &aggregates},
+		"max", "print the maximum of the values of a range at the end",
+	)
+	flags.Var(&aggregateValue{"mean", &aggregates},
+		"mean", "print the mean of the values of a range at the end",
+	)
+	flags.Var(&countValue{&count, &aggregates},
+		"count",
+		"print the number of records, or prefix each distinct selection with it",
+	)
+	flags.Lookup("count").NoOptDefVal = "true"
+	flags.Var(&aggregateValue{"", &aggregates},
+		"agg",
+		"aggregates like sum:5,count,max:3 (count, sum, min, max, mean)",
+	)
+	flags.StringArrayVar(&groupBy,
+		"group-by", groupBy, "aggregate the records grouped by a range",
+	)
+	flags.BoolVar(&unique,
+		"unique", unique, "only print the first occurrence of each selection",
+	)
+	flags.BoolVar(&uniqueAdjacent,
+		"unique-adjacent", uniqueAdjacent,
+		"do not print a selection equal to the previous one",
 	)
 	flags.StringVarP(&format, "format", "f", format, "field printing format")
+	flags.StringVar(&formatDelims,
+		"format-delims", formatDelims,
+		"start and end of the tags of the format separated by a space",
+	)
+	flags.VarP(&outputDelim,
+		"output-delimiter", "o", "separator for printed fields",
+	)
+	flags.Var(&outputTerm, "output-terminator", "terminator for printed records")
+	flags.StringVar(&output,
+		"output", output, "output format (plain, json, ndjson, csv, tsv)",
+	)
 	flags.VarP(&limit, "limit", "n", "number of field to separate")
+	flags.BoolVarP(&nullData,
+		"null-data", "z", nullData, "read NUL terminated records",
+	)
+	flags.Var(&recordSep, "record-separator", "separator for input records")
+	flags.StringVar(&regexRecordSep,
+		"regex-record-separator", regexRecordSep,
+		"regular expression for input record separation",
+	)
+	flags.BoolVarP(&paragraph,
+		"paragraph", "p", paragraph,
+		"read paragraphs separated by blank lines with a field per line",
+	)
+	flags.StringVar(&recordStart,
+		"record-start", recordStart,
+		"regular expression matching the first line of each record",
+	)
+	flags.BoolVar(&print0, "print0", print0, "print NUL terminated records")
+	flags.StringArrayVarP(&files,
+		"file", "F", files, "read input from file (- for stdin)",
+	)
+	flags.BoolVarP(&withFilename,
+		"with-filename", "H", withFilename,
+		"prefix output with file name and line number",
+	)
+
+	Command.MarkFlagsMutuallyExclusive(
+		"shlex", "delimiter", "regex-delimiter", "widths", "table-detect",
+		"csv", "tsv",
+	)
+	Command.MarkFlagsMutuallyExclusive("widths", "limit")
+	Command.MarkFlagsMutuallyExclusive("table-detect", "limit")
+	Command.MarkFlagsMutuallyExclusive("output", "format")
+	Command.MarkFlagsMutuallyExclusive("output", "with-filename")
+	Command.MarkFlagsMutuallyExclusive("print0", "output-terminator")
+	Command.MarkFlagsMutuallyExclusive(
+		"null-data", "record-separator", "regex-record-separator", "paragraph",
+		"csv", "tsv",
+	)
+	Command.MarkFlagsMutuallyExclusive("record-start", "csv", "tsv")
+	Command.MarkFlagsMutuallyExclusive("csv", "limit")
+	Command.MarkFlagsMutuallyExclusive("tsv", "limit")
+	Command.MarkFlagsMutuallyExclusive("unique", "unique-adjacent", "count")
+	Command.MarkFlagsMutuallyExclusive("unique", "format")
+	Command.MarkFlagsMutuallyExclusive("unique-adjacent", "format")
+	for _, name := range []string{
+		"sum", "min", "max", "mean", "count", "agg", "group-by",
+	} {
+		Command.MarkFlagsMutuallyExclusive(name, "format")
+		Command.MarkFlagsMutuallyExclusive(name, "with-filename")
+		Command.MarkFlagsMutuallyExclusive(name, "unique")
+		Command.MarkFlagsMutuallyExclusive(name, "unique-adjacent")
+	}
 
 	if slices.Contains(os.Args, "_carapace") {
-		carapace.Gen(Command)
+		carapace.Gen(Command).FlagCompletion(carapace.ActionMap{
+			"file":      carapace.ActionFiles(),
+			"output":    carapace.ActionValues(outputFormats...),
+			"delimiter": delimiterCompletion(),
+		})
 	} else {
 		handler := slog.New(log.New(os.Stderr))
 		slog.SetDefault(handler)
@@ -51,7 +218,7 @@ func init() {
 // MinimumNArgs returns an error if there is not at least N args.
 func MinimumNArgs(n int) cobra.PositionalArgs {
 	return func(cmd *cobra.Command, args []string) error {
-		if !cmd.Flags().Changed("format") && len(args) < n {
+		if !cmd.Flags().Changed("format") && !aggregating() && len(args) < n {
 			return fmt.Errorf(
 				"requires at least %d arg(s), only received %d", n, len(args),
 			)
@@ -66,7 +233,7 @@ var Command = &cobra.Command{
 	Short: "Extract and print selected fields from each input line",
 	Example: `
 # Extract the second field from ps output (PID) and kill those processes
-ps aux | grep bad-process | field 2 | xargs kill
+ps aux | field -m 11=bad-process 2 | xargs kill
 
 # Print only the usernames (first field) from /etc/passwd and ignore empty lines
 cat /etc/passwd | field -i -d: 1
@@ -77,123 +244,446 @@ ps aux | field -n 11 11
 # Shows the PID and the command from ps command with <PID>:<COMMAND> format
 ps aux | field -n 11 -f "{2}:{11}"
 
+# Split on tabs and print the fields separated by the ASCII unit separator
+field -d tab -o '\x1f' -F data.tsv 1:
+
+# Split on any of comma, semicolon or pipe
+echo 'a,b;c|d' | field -d '[,;|]' 1 4
+
+# Print the GECOS field of each user, which might be empty
+field -k -d: -F /etc/passwd 5
+
+# Print the PID and the command of processes of root using more than 1% CPU
+ps aux | field --header -w '{USER} == "root" && {%CPU} > 1' PID COMMAND
+
+# Print the processes which are not running from /usr/bin
+ps aux | field -v -m '11=^/usr/bin/' 2 11
+
 # Extract multiple fields (user and PID) and print them
 ps aux | field 1 2
 
+# Print the username, user id and shell separated by colons
+field -d: -o: -F /etc/passwd 1 3 7
+
+# Print the values of interleaved key/value output and all fields reversed
+echo 'k1 v1 k2 v2' | field 2::2
+echo 'k1 v1 k2 v2' | field -f '{::-1}'
+
+# Print the first 8 characters of the commit hash and the date of a timestamp
+git log --format='%H %cI' | field '1[:8]' '2[:10]'
+
+# Print everything except the leading timestamp and the log level
+field '!1:2' -F app.log
+
+# Print the base names of files, even if their names contain newlines
+find . -type f -print0 | field -z --print0 -d / -- -1 | xargs -0 echo
+
+# Print the model name line of each processor block of /proc/cpuinfo
+field -p -F /proc/cpuinfo 5
+
+# Print the timestamp and the exception of each log entry with a stack trace
+field --record-start '^\d{4}-\d{2}-\d{2} ' -F app.log 1 2 5
+
 # Extract a directory and get all the deleted files
 rm -vrf bad-directory | field -s -- -1
+
+# Split on a comma or pipe with any amount of surrounding spaces
+echo 'a , b|c' | field -r '\s*[,|]\s*' 1 3
+
+# Print the name and the age columns of a CSV file with quoted cells
+field --csv -F people.csv 1 3
+
+# Split fixed width columns, the last column takes the rest of the line
+df -h | field --widths 15,6,6,6,5,* 1 6
+
+# Detect the columns of an aligned table and select them by the header names
+docker ps | field --table-detect NAMES STATUS
+
+# Select columns by name, the last named column keeps the rest of the line
+ps aux | field --header USER PID COMMAND
+
+# Print the upper cased user and the first 40 characters of the command
+ps aux | field -f '{1|upper}: {11:|trunc:40}'
+
+# Replace the slashes of paths and print a dash for a missing second field
+field -f '{1|replace:"/":"_"} {2|default:"-"}' -F paths.txt
+
+# Number the lines of a file and print their fields count next to them
+field -f '{NR}: {LINE} ({NF} fields)' -F notes.txt
+
+# Print file:line: prefixes that editors can jump to for lines with TODO
+field -m '1:=TODO' -f '{FILENAME}:{FNR}: {LINE}' -F main.go -F cmd.go
+
+# Print each user and PID as a JSON object, with literal braces doubled
+ps aux | field -f '{{"user": "{1}", "pid": {2}}}'
+
+# Use other tag delimiters and separate the fields with a tab
+ps aux | field --format-delims '<< >>' -f '<<1>>\t<<2>>'
+
+# Shows the PID and the command from ps command using the column names
+ps aux | field --header -f "{PID}:{COMMAND}"
+
+# Print the user and the command of each process as JSON objects
+ps aux | field --header --output ndjson USER COMMAND
+
+# Print the number of files and their total and largest size in bytes
+ls -l | field --count --sum 5 --max 5
+
+# Print the number of processes and the total RSS memory of each user
+ps aux | field --header --group-by USER --agg count,sum:RSS
+
+# Print each user running processes once, in order of appearance
+ps aux | field --header --unique USER
+
+# Print the number of processes of each user
+ps aux | field --header --count USER
+
+# Print usernames from multiple files, prefixed with file name and line number
+field -H -d: -F /etc/passwd -F /etc/group 1
 `,
 	Args: MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		writter := bufio.NewWriter(os.Stdout)
 
-		var template *fasttemplate.Template
+		if print0 {
+			outputTerm = "\x00"
+		}
+
+		if output != "plain" && cmd.Flags().Changed("output-delimiter") {
+			return fmt.Errorf("output delimiter can not be used with %s output", output)
+		}
+		if output == "json" && (print0 || cmd.Flags().Changed("output-terminator")) {
+			return errors.New("record terminator can not be used with json output")
+		}
+
+		var template *Template
 		if cmd.Flags().Changed("format") {
-			t, err := fasttemplate.NewTemplate(format, "{", "}")
+			delims := strings.Fields(formatDelims)
+			if len(delims) != 2 {
+				return fmt.Errorf(
+					"invalid format delimiters, expected START END: %q", formatDelims,
+				)
+			}
+			t, err := ParseTemplate(format, delims[0], delims[1])
 			if err != nil {
 				return err
 			}
 			template = t
 		}
 
-		ranges := make([]*Range, len(args))
-		for i, a := range args {
-			r, err := ParseRange(a, false)
+		var filter *Filter
+		if cmd.Flags().Changed("where") {
+			f, err := ParseFilter(where)
+			if err != nil {
+				return err
+			}
+			filter = f
+		}
+		if invertMatch && len(matches) == 0 {
+			return errors.New("invert match requires at least one match")
+		}
+		if len(matches) > 0 {
+			f, err := ParseMatches(matches, invertMatch)
 			if err != nil {
 				return err
 			}
-			ranges[i] = r
+			filter = filter.And(f)
 		}
 
-		const size = 500 * (2 << 19) // 500 MiB
-		reader := bufio.NewReader(os.Stdin)
+		var deduper *Deduper
+		if unique || uniqueAdjacent {
+			deduper = NewDeduper(uniqueAdjacent)
+		}
 
-		buf := bytes.NewBuffer(nil)
+		// counting the distinct selections is the only aggregation of ranges
+		var counter *Counter
+		if count && len(args) > 0 && len(groupBy) == 0 &&
+			len(aggregateSpecs()) == 1 {
+			counter = NewCounter()
+		}
 
-		for {
-			b, prefixed, err := reader.ReadLine()
+		var aggregator *Aggregator
+		if aggregating() && counter == nil {
+			if len(args) > 0 {
+				return errors.New("ranges can not be selected while aggregating")
+			}
+			a, err := NewAggregator(aggregateSpecs(), groupBy)
 			if err != nil {
-				if errors.Is(err, io.EOF) {
-					break
-				}
 				return err
 			}
+			aggregator = a
+		}
 
-			if buf.Len()+len(b) > size {
-				return fmt.Errorf("line is too big")
+		// ranges can only be parsed after reading the column names
+		var columns []string
+		var ranges Ranges
+		// selector selects the columns chosen from the header, if there is one
+		var selector *ColumnSelector
+		hasHeader := header || tableDetect
+		if !hasHeader {
+			r, err := parseSelection(args, nil)
+			if err != nil {
+				return err
 			}
+			ranges = r
 
-			if prefixed {
-				buf.Write(b)
-				continue
+			if filter != nil {
+				if err := filter.Bind(nil); err != nil {
+					return err
+				}
 			}
-
-			if buf.Len() > 0 {
-				b = buf.Bytes()
-				buf.Reset()
+			if aggregator != nil {
+				if err := aggregator.Bind(nil); err != nil {
+					return err
+				}
 			}
-
-			var fields []string
-			if shell {
-				s, err := shlex.Split(string(b))
-				if err != nil {
-					slog.Error("Failed to parse qouted field", "error", err)
-					continue
+			if template != nil {
+				if err := template.Bind(nil); err != nil {
+					return err
 				}
-				fields = s.Strings()
-			} else if cmd.Flags().Changed("delimiter") {
-				fields = FieldN(b, delimiter, limit.Int())
-			} else {
-				fields = FieldNFunc(b, unicode.IsSpace, limit.Int())
 			}
+		}
 
-			selected := make([]string, 0, 10)
-			for r := range slices.Values(ranges) {
-				selected = append(selected, r.Select(fields)...)
-			}
+		printer, err := NewPrinter(writter, output)
+		if err != nil {
+			return err
+		}
 
+		read, err := NewRecordReader(cmd)
+		if err != nil {
+			return err
+		}
+
+		// nr is the number of records read from all inputs
+		nr := 0
+
+		process := func(name string, fnr int, raw []byte, fields []string) error {
 			if template == nil {
+				selected := selectFields(ranges, fields)
+				if selector != nil {
+					selected = selector.Select(fields)
+				}
+
 				if ignoreEmpty && len(selected) == 0 {
-					continue
+					return nil
+				}
+
+				if deduper != nil && deduper.Seen(selected) {
+					return nil
+				}
+
+				if counter != nil {
+					counter.Add(selected)
+					return nil
 				}
 
-				fprintlnStr(writter, selected)
-				if err := writter.Flush(); err != nil {
+				if withFilename {
+					fmt.Fprintf(writter, "%s:%d:", name, fnr)
+				}
+
+				if err := printer.Print(selected); err != nil {
 					return err
 				}
-				continue
+				return writter.Flush()
 			}
 
-			selector := func(w io.Writer, tag string) (int, error) {
-				r, err := ParseRange(tag, false)
-				if err != nil {
-					return 0, err
+			vars := func(tag string) string {
+				switch tag {
+				case "NR":
+					return strconv.Itoa(nr)
+				case "NF":
+					return strconv.Itoa(len(fields))
+				case "LINE":
+					return string(raw)
+				case "FILENAME":
+					return name
+				case "FNR":
+					return strconv.Itoa(fnr)
 				}
-				return fprintlnStr(w, r.Select(fields))
+				return ""
 			}
 
-			if _, err := template.ExecuteFunc(writter, selector); err != nil {
-				slog.Error("Failed execute format template", "error", err)
-				continue
+			if withFilename {
+				fmt.Fprintf(writter, "%s:%d:", name, fnr)
 			}
 
-			if err := writter.Flush(); err != nil {
+			if err := template.Execute(writter, fields, vars); err != nil {
 				return err
 			}
+
+			io.WriteString(writter, string(outputTerm))
+			return writter.Flush()
 		}
 
-		return nil
+		inputs := files
+		if len(inputs) == 0 {
+			inputs = []string{stdinName}
+		}
+
+		headerPrinted := false
+		for name := range slices.Values(inputs) {
+			f, err := openInput(name)
+			if err != nil {
+				return err
+			}
+
+			fnr := 0
+			err = read(f, func(raw []byte, fields []string) error {
+				nr++
+				fnr++
+				if hasHeader && fnr == 1 {
+					r, err := parseSelection(args, fields)
+					if err != nil {
+						return err
+					}
+					columns, ranges = fields, r
+
+					if filter != nil {
+						if err := filter.Bind(columns); err != nil {
+							return err
+						}
+					}
+
+					if aggregator != nil {
+						return aggregator.Bind(columns)
+					}
+
+					if template != nil {
+						if err := template.Bind(columns); err != nil {
+							return err
+						}
+					}
+
+					if template == nil {
+						selector = NewColumnSelector(ranges, len(columns), complement)
+						names := selector.Names(columns)
+						if counter != nil {
+							names = append([]string{"count"}, names...)
+						}
+						if err := printer.Header(names); err != nil {
+							return err
+						}
+						return writter.Flush()
+					}
+
+					// every file has a header but only the first one is printed
+					if headerPrinted {
+						return nil
+					}
+					headerPrinted = true
+				} else if filter != nil && !filter.Match(fields) {
+					return nil
+				} else if aggregator != nil {
+					aggregator.Add(fields)
+					return nil
+				}
+				return process(name, fnr, raw, fields)
+			})
+			f.Close()
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+
+		if counter != nil {
+			for row := range slices.Values(counter.Rows()) {
+				if err := printer.Print(row); err != nil {
+					return err
+				}
+			}
+		}
+
+		if aggregator != nil {
+			if hasHeader {
+				if err := printer.Header(aggregator.Names()); err != nil {
+					return err
+				}
+			}
+			for row := range slices.Values(aggregator.Rows()) {
+				if err := printer.Print(row); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := printer.Close(); err != nil {
+			return err
+		}
+		return writter.Flush()
 	},
 }
 
-func fprintlnStr(w io.Writer, values []string) (int, error) {
-	if len(values) == 0 {
-		return w.Write([]byte{'\n'})
+// aggregating reports whether any of the aggregation flags is set
+func aggregating() bool {
+	return len(aggregates) > 0 || len(groupBy) > 0
+}
+
+// aggregateSpecs returns the aggregate specs, like "sum:5", of the
+// aggregation flags in the order of the command line. Groups are counted if
+// there is no other aggregate.
+func aggregateSpecs() []string {
+	if len(aggregates) == 0 {
+		return []string{"count"}
+	}
+	return aggregates
+}
+
+// selectFields returns the fields selected by the ranges, or all the other
+// fields if the selection is complemented
+func selectFields(ranges Ranges, fields []string) []string {
+	if complement {
+		return ranges.Complement(fields)
+	}
+	return ranges.Select(fields)
+}
+
+// parseRange parses str into a Range with the options given by the flags
+func parseRange(str string, header []string) (*Range, error) {
+	r, err := ParseRangeHeader(str, false, header)
+	if err != nil {
+		return nil, err
+	}
+	r.Bytes = byteChars
+	return r, nil
+}
+
+// parseRanges parses each of the args into a Range. Column names are resolved
+// against header if it is not nil.
+func parseRanges(args []string, header []string) (Ranges, error) {
+	ranges := make(Ranges, len(args))
+	for i, a := range args {
+		r, err := parseRange(a, header)
+		if err != nil {
+			return nil, err
+		}
+		ranges[i] = r
+	}
+	return ranges, nil
+}
+
+// parseSelection parses the positional args into the ranges of the selection.
+// The characters of a complemented selection can not be sliced.
+func parseSelection(args []string, header []string) (Ranges, error) {
+	ranges, err := parseRanges(args, header)
+	if err != nil {
+		return nil, err
 	}
+	if complement {
+		for i, r := range ranges {
+			if r.Chars != nil {
+				return nil, fmt.Errorf("cannot slice complemented range: %q", args[i])
+			}
+		}
+	}
+	return ranges, nil
+}
+
+// writeFields writes values to w separated by the output delimiter
+func writeFields(w io.Writer, values []string) (int, error) {
 	var written int
 	for i, str := range values {
 		if i > 0 {
-			n, err := w.Write([]byte{' '})
+			n, err := io.WriteString(w, string(outputDelim))
 			if err != nil {
 				return written + n, err
 			}
@@ -205,6 +695,5 @@ func fprintlnStr(w io.Writer, values []string) (int, error) {
 		}
 		written += n
 	}
-	n, err := w.Write([]byte{'\n'})
-	return written + n, err
+	return written, nil
 }
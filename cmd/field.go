@@ -2,7 +2,11 @@ package cmd
 
 import (
 	"bytes"
+	"regexp"
+	"strings"
 	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
 )
 
 const minResultSize int = 30
@@ -97,7 +101,208 @@ func FieldNFunc(s []byte, pred Pred, n int) []string {
 			i += sz2
 		}
 
-		if n > 0 && i < size-1 && found+1 == n {
+		if n > 0 && i < len(s) && found+1 == n {
+			result = append(result, string(s[i:]))
+			return result
+		}
+		start = i
+	}
+
+	if start < len(s) {
+		result = append(result, string(s[start:]))
+	}
+
+	return result
+}
+
+// FieldNRegexp splits s into at most n fields, separated by the matches of re.
+// If n < 0, it returns all fields. Consecutive matches are treated as one and
+// empty matches are ignored.
+func FieldNRegexp(s []byte, re *regexp.Regexp, n int) []string {
+	if len(s) == 0 || re == nil {
+		return nil
+	}
+	if n == 1 {
+		return []string{string(s)}
+	}
+
+	matches := re.FindAllIndex(s, -1)
+	result := make([]string, 0, minResultSize)
+	start := 0
+	found := 0
+
+	for i := 0; i < len(matches); i++ {
+		from, to := matches[i][0], matches[i][1]
+		if from == to {
+			continue
+		}
+
+		if start < from {
+			result = append(result, string(s[start:from]))
+			found++
+		}
+
+		// skip consecutive separators
+		for i+1 < len(matches) && matches[i+1][0] == to {
+			i++
+			to = matches[i][1]
+		}
+		start = to
+
+		if n > 0 && start < len(s) && found+1 == n {
+			result = append(result, string(s[start:]))
+			return result
+		}
+	}
+
+	if start < len(s) {
+		result = append(result, string(s[start:]))
+	}
+
+	return result
+}
+
+// FieldWidths splits s into fields of the given widths measured in display
+// cells. A negative width takes the remainder of s. Characters are never split
+// and belong to the field in which they start. Surrounding spaces are trimmed
+// from each field.
+func FieldWidths(s []byte, widths []int) []string {
+	if len(s) == 0 || len(widths) == 0 {
+		return nil
+	}
+
+	result := make([]string, 0, len(widths))
+	start := 0
+	cells := 0
+	// the boundaries are at fixed cells, even if a wide character crosses one
+	boundary := 0
+
+	for _, width := range widths {
+		if start >= len(s) {
+			break
+		}
+		if width < 0 {
+			result = append(result, strings.TrimSpace(string(s[start:])))
+			return result
+		}
+
+		boundary += width
+		i := start
+		for i < len(s) {
+			r, size := utf8.DecodeRune(s[i:])
+			w := runewidth.RuneWidth(r)
+			// zero width characters stay with the preceding character
+			if cells >= boundary && w > 0 {
+				break
+			}
+			cells += w
+			i += size
+		}
+
+		result = append(result, strings.TrimSpace(string(s[start:i])))
+		start = i
+	}
+
+	return result
+}
+
+// SplitN splits s into at most n fields separated by the delimiter. If n < 0,
+// it returns all fields. Unlike FieldN, every occurrence of the delimiter
+// separates exactly one field, so empty fields are kept (like strings.SplitN).
+func SplitN(s []byte, delimiter string, n int) []string {
+	if len(s) == 0 || delimiter == "" {
+		return nil
+	}
+	if n <= 0 {
+		n = -1
+	}
+
+	parts := bytes.SplitN(s, []byte(delimiter), n)
+	result := make([]string, len(parts))
+	for i, part := range parts {
+		result[i] = string(part)
+	}
+	return result
+}
+
+// SplitNFunc splits s into at most n fields, separated by runes where pred(r)
+// == true. If n < 0, it returns all fields. Every separator separates exactly
+// one field, so empty fields are kept.
+func SplitNFunc(s []byte, pred Pred, n int) []string {
+	if len(s) == 0 || pred == nil {
+		return nil
+	}
+
+	result := make([]string, 0, minResultSize)
+	start := 0
+
+	for i := 0; i < len(s); {
+		if n > 0 && len(result)+1 == n {
+			break
+		}
+
+		r, size := utf8.DecodeRune(s[i:])
+		i += size
+		if pred(r) {
+			result = append(result, string(s[start:i-size]))
+			start = i
+		}
+	}
+
+	return append(result, string(s[start:]))
+}
+
+// SplitNRegexp splits s into at most n fields, separated by the matches of re.
+// If n < 0, it returns all fields. Every match separates exactly one field, so
+// empty fields are kept (like regexp.Regexp.Split).
+func SplitNRegexp(s []byte, re *regexp.Regexp, n int) []string {
+	if len(s) == 0 || re == nil {
+		return nil
+	}
+	if n <= 0 {
+		n = -1
+	}
+	return re.Split(string(s), n)
+}
+
+// FieldNAny splits s into at most n fields separated by any of the delimiters.
+// If n < 0, it returns all fields. Consecutive delimiters are treated as one
+// and the longest delimiter is preferred when several of them match.
+func FieldNAny(s []byte, delimiters []string, n int) []string {
+	if len(s) == 0 || len(delimiters) == 0 {
+		return nil
+	}
+	if n == 1 {
+		return []string{string(s)}
+	}
+
+	result := make([]string, 0, minResultSize)
+	start := 0
+	found := 0
+
+	for i := 0; i < len(s); {
+		size := matchAny(s[i:], delimiters)
+		if size == 0 {
+			i++
+			continue
+		}
+
+		if start < i {
+			result = append(result, string(s[start:i]))
+			found++
+		}
+
+		i += size
+		// skip consecutive separators
+		for i < len(s) {
+			size := matchAny(s[i:], delimiters)
+			if size == 0 {
+				break
+			}
+			i += size
+		}
+
+		if n > 0 && i < len(s) && found+1 == n {
 			result = append(result, string(s[i:]))
 			return result
 		}
@@ -110,3 +315,45 @@ func FieldNFunc(s []byte, pred Pred, n int) []string {
 
 	return result
 }
+
+// SplitNAny splits s into at most n fields separated by any of the
+// delimiters. If n < 0, it returns all fields. Every occurrence of a delimiter
+// separates exactly one field, so empty fields are kept.
+func SplitNAny(s []byte, delimiters []string, n int) []string {
+	if len(s) == 0 || len(delimiters) == 0 {
+		return nil
+	}
+
+	result := make([]string, 0, minResultSize)
+	start := 0
+
+	for i := 0; i < len(s); {
+		if n > 0 && len(result)+1 == n {
+			break
+		}
+
+		size := matchAny(s[i:], delimiters)
+		if size == 0 {
+			i++
+			continue
+		}
+
+		result = append(result, string(s[start:i]))
+		i += size
+		start = i
+	}
+
+	return append(result, string(s[start:]))
+}
+
+// matchAny returns the length of the longest of the delimiters s starts with,
+// or zero if s does not start with any of them
+func matchAny(s []byte, delimiters []string) int {
+	size := 0
+	for _, delim := range delimiters {
+		if len(delim) > size && bytes.HasPrefix(s, []byte(delim)) {
+			size = len(delim)
+		}
+	}
+	return size
+}
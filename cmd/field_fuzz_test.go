@@ -89,3 +89,53 @@ func FuzzFieldNFunc_Stdlib(f *testing.F) {
 		}
 	})
 }
+
+func FuzzSplitN_Stdlib(f *testing.F) {
+	f.Add([]byte("a::b:c"), ":", 3)
+	f.Add([]byte(",a,"), ",", -1)
+
+	f.Fuzz(func(t *testing.T, s []byte, delim string, n int) {
+		if len(s) == 0 || delim == "" || n == 0 {
+			return
+		}
+
+		got := SplitN(s, delim, n)
+		want := strings.SplitN(string(s), delim, n)
+
+		if len(got) != len(want) {
+			t.Fatalf("len mismatch: %q vs %q", got, want)
+		}
+
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("mismatch at %d: %q vs %q", i, got[i], want[i])
+			}
+		}
+	})
+}
+
+func FuzzSplitNFunc_Stdlib(f *testing.F) {
+	isSpace := func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\n'
+	}
+
+	f.Fuzz(func(t *testing.T, s []byte) {
+		if len(s) == 0 || !utf8.Valid(s) {
+			return
+		}
+
+		got := SplitNFunc(s, isSpace, -1)
+		replacer := strings.NewReplacer("\t", " ", "\n", " ")
+		want := strings.Split(replacer.Replace(string(s)), " ")
+
+		if len(got) != len(want) {
+			t.Fatalf("len mismatch: %q vs %q", got, want)
+		}
+
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("mismatch at %d: %q vs %q", i, got[i], want[i])
+			}
+		}
+	})
+}
@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"reflect"
+	"regexp"
 	"testing"
+	"unicode"
 )
 
 func TestFieldN(t *testing.T) {
@@ -60,3 +62,320 @@ func TestFieldN(t *testing.T) {
 		})
 	}
 }
+
+func TestFieldNFunc(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		limit int
+		want  []string
+	}{
+		{
+			name:  "basic unlimited split on space",
+			input: "  a \t b c  ",
+			limit: -1,
+			want:  []string{"a", "b", "c"},
+		},
+		{
+			name:  "limit keeps remainder intact",
+			input: "a b  likes cats a lot",
+			limit: 3,
+			want:  []string{"a", "b", "likes cats a lot"},
+		},
+		{
+			name:  "limit larger than number of fields",
+			input: "a b c",
+			limit: 5,
+			want:  []string{"a", "b", "c"},
+		},
+		{
+			name:  "trailing separators with limit",
+			input: "a b  ",
+			limit: 2,
+			want:  []string{"a", "b  "},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FieldNFunc([]byte(tt.input), unicode.IsSpace, tt.limit)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FieldNFunc(%q, %d) = %q, want %q",
+					tt.input, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldNRegexp(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		pattern string
+		limit   int
+		want    []string
+	}{
+		{
+			name:    "varying separator",
+			input:   "a, b,c ,  d",
+			pattern: `\s*,\s*`,
+			limit:   -1,
+			want:    []string{"a", "b", "c", "d"},
+		},
+		{
+			name:    "leading and trailing separators are ignored",
+			input:   "| a | b |",
+			pattern: `\s*\|\s*`,
+			limit:   -1,
+			want:    []string{"a", "b"},
+		},
+		{
+			name:    "consecutive matches are treated as one",
+			input:   "a,;,b",
+			pattern: `[,;]`,
+			limit:   -1,
+			want:    []string{"a", "b"},
+		},
+		{
+			name:    "limit keeps remainder intact",
+			input:   "a, b, c, d",
+			pattern: `,\s*`,
+			limit:   2,
+			want:    []string{"a", "b, c, d"},
+		},
+		{
+			name:    "empty matches are ignored",
+			input:   "a1b22c",
+			pattern: `\d*`,
+			limit:   -1,
+			want:    []string{"a", "b", "c"},
+		},
+		{
+			name:    "no match returns whole input",
+			input:   "abc",
+			pattern: `,`,
+			limit:   -1,
+			want:    []string{"abc"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re := regexp.MustCompile(tt.pattern)
+			got := FieldNRegexp([]byte(tt.input), re, tt.limit)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FieldNRegexp(%q, %q, %d) = %q, want %q",
+					tt.input, tt.pattern, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldWidths(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		widths []int
+		want   []string
+	}{
+		{
+			name:   "values with spaces",
+			input:  "/dev/sda1  2 hours ago  /",
+			widths: []int{11, 12, 2},
+			want:   []string{"/dev/sda1", "2 hours ago", "/"},
+		},
+		{
+			name:   "rest of the line",
+			input:  "ab  cd ef gh",
+			widths: []int{4, -1},
+			want:   []string{"ab", "cd ef gh"},
+		},
+		{
+			name:   "text beyond the widths is ignored",
+			input:  "ab  cd  ef",
+			widths: []int{4, 4},
+			want:   []string{"ab", "cd"},
+		},
+		{
+			name:   "wide character does not shift later boundaries",
+			input:  "中文a   bcd  x",
+			widths: []int{3, 3, 3, -1},
+			want:   []string{"中文", "a", "b", "cd  x"},
+		},
+		{
+			name:   "short line",
+			input:  "ab  c",
+			widths: []int{4, 4, 4},
+			want:   []string{"ab", "c"},
+		},
+		{
+			name:   "wide characters count as two cells",
+			input:  "日本語ab  x",
+			widths: []int{4, 4, -1},
+			want:   []string{"日本", "語ab", "x"},
+		},
+		{
+			name:   "wide character crossing a boundary",
+			input:  "a日b",
+			widths: []int{2, 2},
+			want:   []string{"a日", "b"},
+		},
+		{
+			name:   "combining characters stay with the base",
+			input:  "aéb",
+			widths: []int{2, 1},
+			want:   []string{"aé", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FieldWidths([]byte(tt.input), tt.widths)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FieldWidths(%q, %v) = %q, want %q",
+					tt.input, tt.widths, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitN(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		split func(s []byte, n int) []string
+		limit int
+		want  []string
+	}{
+		{
+			name:  "empty fields between delimiters",
+			input: "root:x:0:0::/root:/bin/bash",
+			split: func(s []byte, n int) []string { return SplitN(s, ":", n) },
+			limit: -1,
+			want:  []string{"root", "x", "0", "0", "", "/root", "/bin/bash"},
+		},
+		{
+			name:  "leading and trailing delimiters",
+			input: ",a,",
+			split: func(s []byte, n int) []string { return SplitN(s, ",", n) },
+			limit: -1,
+			want:  []string{"", "a", ""},
+		},
+		{
+			name:  "limit keeps remainder intact",
+			input: "a::b:c",
+			split: func(s []byte, n int) []string { return SplitN(s, ":", n) },
+			limit: 3,
+			want:  []string{"a", "", "b:c"},
+		},
+		{
+			name:  "every space separates a field",
+			input: "a  b ",
+			split: func(s []byte, n int) []string {
+				return SplitNFunc(s, unicode.IsSpace, n)
+			},
+			limit: -1,
+			want:  []string{"a", "", "b", ""},
+		},
+		{
+			name:  "func limit keeps remainder intact",
+			input: "a  b c",
+			split: func(s []byte, n int) []string {
+				return SplitNFunc(s, unicode.IsSpace, n)
+			},
+			limit: 2,
+			want:  []string{"a", " b c"},
+		},
+		{
+			name:  "every match separates a field",
+			input: "a, ,b",
+			split: func(s []byte, n int) []string {
+				return SplitNRegexp(s, regexp.MustCompile(`,\s*`), n)
+			},
+			limit: -1,
+			want:  []string{"a", "", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.split([]byte(tt.input), tt.limit)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("split(%q, %d) = %q, want %q",
+					tt.input, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldNAny(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		delimiters []string
+		keepEmpty  bool
+		limit      int
+		want       []string
+	}{
+		{
+			name:       "any of the delimiters",
+			input:      "a,b;c|d",
+			delimiters: []string{",", ";", "|"},
+			limit:      -1,
+			want:       []string{"a", "b", "c", "d"},
+		},
+		{
+			name:       "mixed consecutive delimiters are treated as one",
+			input:      ",a,;b|",
+			delimiters: []string{",", ";", "|"},
+			limit:      -1,
+			want:       []string{"a", "b"},
+		},
+		{
+			name:       "longest delimiter is preferred",
+			input:      "a::b:c",
+			delimiters: []string{":", "::"},
+			keepEmpty:  true,
+			limit:      -1,
+			want:       []string{"a", "b", "c"},
+		},
+		{
+			name:       "limit keeps remainder intact",
+			input:      "a,b;c|d",
+			delimiters: []string{",", ";"},
+			limit:      2,
+			want:       []string{"a", "b;c|d"},
+		},
+		{
+			name:       "empty fields are kept",
+			input:      ",a,;b|",
+			delimiters: []string{",", ";", "|"},
+			keepEmpty:  true,
+			limit:      -1,
+			want:       []string{"", "a", "", "b", ""},
+		},
+		{
+			name:       "limit with empty fields",
+			input:      "a;;b,c",
+			delimiters: []string{",", ";"},
+			keepEmpty:  true,
+			limit:      3,
+			want:       []string{"a", "", "b,c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			if tt.keepEmpty {
+				got = SplitNAny([]byte(tt.input), tt.delimiters, tt.limit)
+			} else {
+				got = FieldNAny([]byte(tt.input), tt.delimiters, tt.limit)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("split(%q, %q, %d) = %q, want %q",
+					tt.input, tt.delimiters, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
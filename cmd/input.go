@@ -0,0 +1,325 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+)
+
+// stdinName is the file name used to refer to the standard input
+const stdinName = "-"
+
+// maxLineSize is the maximum size of a single input line
+const maxLineSize = 500 * (2 << 19) // 500 MiB
+
+// RecordReader reads records from r and calls fn with each unsplit record and
+// its fields. Reading stops at the first error returned by fn.
+type RecordReader func(r io.Reader, fn func(raw []byte, fields []string) error) error
+
+// rawReader reads r record by record and calls fn with each unsplit record.
+// Reading stops at the first error returned by fn.
+type rawReader func(r io.Reader, fn func(b []byte) error) error
+
+// NewRecordReader returns the RecordReader selected by the flags of cmd
+func NewRecordReader(cmd *cobra.Command) (RecordReader, error) {
+	read, err := newRawReader(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case csvInput:
+		return csvReader(','), nil
+	case tsvInput:
+		return csvReader('\t'), nil
+	case tableDetect:
+		return tableReader(read), nil
+	}
+
+	split, err := NewSplitter(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	// The last column of the header holds the remainder of the line
+	implicitLimit := header && !cmd.Flags().Changed("limit")
+
+	return func(r io.Reader, fn func(raw []byte, fields []string) error) error {
+		n := limit.Int()
+		first := true
+		return read(r, func(b []byte) error {
+			fields, err := split(b, n)
+			if err != nil {
+				slog.Error("Failed to split fields", "error", err)
+				return nil
+			}
+			if first && implicitLimit && len(fields) > 0 {
+				n = len(fields)
+			}
+			first = false
+			return fn(b, fields)
+		})
+	}, nil
+}
+
+// newRawReader returns the rawReader selected by the flags of cmd
+func newRawReader(cmd *cobra.Command) (rawReader, error) {
+	flags := cmd.Flags()
+
+	var split bufio.SplitFunc
+	switch {
+	case nullData:
+		split = scanSeparator([]byte{0})
+	case flags.Changed("record-separator"):
+		if recordSep == "" {
+			return nil, errors.New("empty record separator")
+		}
+		split = scanSeparator([]byte(recordSep))
+	case flags.Changed("regex-record-separator"):
+		re, err := regexp.Compile(regexRecordSep)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex record separator: %w", err)
+		}
+		split = scanRegexp(re)
+	case paragraph:
+		split = scanParagraph
+	}
+
+	var read rawReader = readLines
+	if split != nil {
+		read = func(r io.Reader, fn func(b []byte) error) error {
+			return readRecords(r, split, fn)
+		}
+	}
+
+	if flags.Changed("record-start") {
+		re, err := regexp.Compile(recordStart)
+		if err != nil {
+			return nil, fmt.Errorf("invalid record start: %w", err)
+		}
+		read = foldRecords(read, re)
+	}
+
+	return read, nil
+}
+
+// foldRecords returns a rawReader which joins every record read by read, that
+// does not match start, to the previous record with a newline. This is useful
+// for log entries spanning multiple lines, like stack traces.
+func foldRecords(read rawReader, start *regexp.Regexp) rawReader {
+	return func(r io.Reader, fn func(b []byte) error) error {
+		buf := bytes.NewBuffer(nil)
+		pending := false
+
+		err := read(r, func(b []byte) error {
+			switch {
+			case pending && start.Match(b):
+				if err := fn(buf.Bytes()); err != nil {
+					return err
+				}
+				buf.Reset()
+			case pending:
+				buf.WriteByte('\n')
+			}
+
+			if buf.Len()+len(b) > maxLineSize {
+				return fmt.Errorf("record is too big")
+			}
+
+			buf.Write(b)
+			pending = true
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if pending {
+			return fn(buf.Bytes())
+		}
+		return nil
+	}
+}
+
+// csvReader returns a RecordReader for RFC 4180 records separated by comma.
+// Quoted cells may contain the separator, escaped quotes and line breaks.
+func csvReader(comma rune) RecordReader {
+	return func(r io.Reader, fn func(raw []byte, fields []string) error) error {
+		// the raw text of each record is kept from the read input by its offset
+		buf := bytes.NewBuffer(nil)
+		reader := csv.NewReader(io.TeeReader(r, buf))
+		reader.Comma = comma
+		reader.FieldsPerRecord = -1
+
+		var offset int64
+		for {
+			record, err := reader.Read()
+			end := reader.InputOffset()
+			raw := buf.Next(int(end - offset))
+			offset = end
+
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				var perr *csv.ParseError
+				if errors.As(err, &perr) {
+					slog.Error("Failed to parse record", "error", err)
+					continue
+				}
+				return err
+			}
+
+			// blank lines before a record are skipped by the csv reader
+			raw = bytes.TrimLeft(raw, "\r\n")
+			raw = bytes.TrimSuffix(bytes.TrimSuffix(raw, []byte("\n")), []byte("\r"))
+			if err := fn(raw, record); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// openInput opens the named file for reading. The name "-" refers to the
+// standard input, which is never closed by the returned closer.
+func openInput(name string) (io.ReadCloser, error) {
+	if name == stdinName {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(name)
+}
+
+// readLines reads r line by line and calls fn with each line excluding the
+// trailing line ending. Reading stops at the first error returned by fn.
+func readLines(r io.Reader, fn func(line []byte) error) error {
+	reader := bufio.NewReader(r)
+	buf := bytes.NewBuffer(nil)
+
+	for {
+		b, prefixed, err := reader.ReadLine()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		if buf.Len()+len(b) > maxLineSize {
+			return fmt.Errorf("line is too big")
+		}
+
+		if prefixed {
+			buf.Write(b)
+			continue
+		}
+
+		if buf.Len() > 0 {
+			buf.Write(b)
+			b = buf.Bytes()
+			buf.Reset()
+		}
+
+		if err := fn(b); err != nil {
+			return err
+		}
+	}
+}
+
+// readRecords reads r as records separated by split and calls fn with each of
+// them. Reading stops at the first error returned by fn.
+func readRecords(r io.Reader, split bufio.SplitFunc, fn func(b []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLineSize)
+	scanner.Split(split)
+
+	for scanner.Scan() {
+		if err := fn(scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// scanSeparator returns a bufio.SplitFunc which splits records terminated by
+// sep
+func scanSeparator(sep []byte) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+		if i := bytes.Index(data, sep); i >= 0 {
+			return i + len(sep), data[:i], nil
+		}
+		if atEOF {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
+
+// scanRegexp returns a bufio.SplitFunc which splits records separated by the
+// matches of re. Empty matches are ignored.
+func scanRegexp(re *regexp.Regexp) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+		for off := 0; off < len(data); {
+			loc := re.FindIndex(data[off:])
+			if loc == nil {
+				break
+			}
+			start, end := off+loc[0], off+loc[1]
+			if start == end {
+				_, size := utf8.DecodeRune(data[end:])
+				off = end + size
+				continue
+			}
+			// the match might continue in the data that is not read yet
+			if end == len(data) && !atEOF {
+				break
+			}
+			return end, data[:start], nil
+		}
+		if atEOF {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
+
+// paragraphSeparator matches one or more blank lines
+var paragraphSeparator = regexp.MustCompile(`\r?\n(?:[ \t]*\r?\n)+`)
+
+// scanParagraph is a bufio.SplitFunc which splits paragraphs separated by
+// blank lines
+func scanParagraph(data []byte, atEOF bool) (int, []byte, error) {
+	// skip the blank lines before the first paragraph
+	start := 0
+	for start < len(data) && (data[start] == '\n' || data[start] == '\r') {
+		start++
+	}
+
+	advance, token, err := scanParagraphBody(data[start:], atEOF)
+	if err != nil || token == nil {
+		return start + advance, nil, err
+	}
+
+	token = bytes.TrimRight(token, "\r\n")
+	if len(token) == 0 {
+		return start + advance, nil, nil
+	}
+	return start + advance, token, nil
+}
+
+// scanParagraphBody splits the paragraphs after the leading blank lines
+var scanParagraphBody = scanRegexp(paragraphSeparator)
@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"bufio"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadLines(t *testing.T) {
+	long := strings.Repeat("x", bufio.MaxScanTokenSize)
+
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "lines with trailing newline",
+			input: "a b\nc d\n",
+			want:  []string{"a b", "c d"},
+		},
+		{
+			name:  "last line without newline",
+			input: "a b\nc d",
+			want:  []string{"a b", "c d"},
+		},
+		{
+			name:  "crlf line endings",
+			input: "a b\r\nc d\r\n",
+			want:  []string{"a b", "c d"},
+		},
+		{
+			name:  "line longer than reader buffer",
+			input: long + "\nshort\n",
+			want:  []string{long, "short"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			err := readLines(strings.NewReader(tt.input), func(b []byte) error {
+				got = append(got, string(b))
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("readLines() failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	tests := []struct {
+		name  string
+		comma rune
+		input string
+		want  [][]string
+	}{
+		{
+			name:  "quoted separator",
+			comma: ',',
+			input: "\"Smith, John\",42\n",
+			want:  [][]string{{"Smith, John", "42"}},
+		},
+		{
+			name:  "escaped quotes",
+			comma: ',',
+			input: "\"say \"\"hi\"\"\",1\n",
+			want:  [][]string{{"say \"hi\"", "1"}},
+		},
+		{
+			name:  "multi-line cell",
+			comma: ',',
+			input: "\"a\nb\",c\nd,e\n",
+			want:  [][]string{{"a\nb", "c"}, {"d", "e"}},
+		},
+		{
+			name:  "variable number of fields",
+			comma: ',',
+			input: "a,b,c\nd\n",
+			want:  [][]string{{"a", "b", "c"}, {"d"}},
+		},
+		{
+			name:  "empty cells are preserved",
+			comma: ',',
+			input: "a,,c\n",
+			want:  [][]string{{"a", "", "c"}},
+		},
+		{
+			name:  "tab separated",
+			comma: '\t',
+			input: "a b\t\"c\td\"\n",
+			want:  [][]string{{"a b", "c\td"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][]string
+			read := csvReader(tt.comma)
+			err := read(strings.NewReader(tt.input), func(_ []byte, fields []string) error {
+				got = append(got, fields)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("csvReader() failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("csvReader() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSVReader_Raw(t *testing.T) {
+	input := "\"Smith, John\",42\r\n\n\"a\nb\",c\nd,\"e\"\"\""
+	want := []string{"\"Smith, John\",42", "\"a\nb\",c", "d,\"e\"\"\""}
+
+	var got []string
+	read := csvReader(',')
+	err := read(strings.NewReader(input), func(raw []byte, _ []string) error {
+		got = append(got, string(raw))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("csvReader() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("csvReader() raw = %q, want %q", got, want)
+	}
+}
+
+func TestReadRecords(t *testing.T) {
+	tests := []struct {
+		name  string
+		split bufio.SplitFunc
+		input string
+		want  []string
+	}{
+		{
+			name:  "nul terminated",
+			split: scanSeparator([]byte{0}),
+			input: "a b\x00c\nd\x00",
+			want:  []string{"a b", "c\nd"},
+		},
+		{
+			name:  "last record without terminator",
+			split: scanSeparator([]byte{0}),
+			input: "a\x00b",
+			want:  []string{"a", "b"},
+		},
+		{
+			name:  "empty records",
+			split: scanSeparator([]byte{0}),
+			input: "\x00a\x00",
+			want:  []string{"", "a"},
+		},
+		{
+			name:  "multi-byte separator",
+			split: scanSeparator([]byte("--")),
+			input: "a-b--c--",
+			want:  []string{"a-b", "c"},
+		},
+		{
+			name:  "regular expression separator",
+			split: scanRegexp(regexp.MustCompile(`;\s*`)),
+			input: "a; b;c;   d",
+			want:  []string{"a", "b", "c", "d"},
+		},
+		{
+			name:  "empty matches of regular expression separator",
+			split: scanRegexp(regexp.MustCompile(`;*`)),
+			input: "ab;;c;d",
+			want:  []string{"ab", "c", "d"},
+		},
+		{
+			name:  "many small records",
+			split: scanRegexp(regexp.MustCompile(`;`)),
+			input: strings.Repeat("abc;", 10000),
+			want:  slices.Repeat([]string{"abc"}, 10000),
+		},
+		{
+			name:  "many small paragraphs",
+			split: scanParagraph,
+			input: strings.Repeat("a\nb\n\n", 10000),
+			want:  slices.Repeat([]string{"a\nb"}, 10000),
+		},
+		{
+			name:  "paragraphs",
+			split: scanParagraph,
+			input: "\n\na\nb\n\n  \n\nc d\ne\n\n",
+			want:  []string{"a\nb", "c d\ne"},
+		},
+		{
+			name:  "paragraph without trailing newline",
+			split: scanParagraph,
+			input: "a\r\n\r\nb",
+			want:  []string{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			err := readRecords(strings.NewReader(tt.input), tt.split, func(b []byte) error {
+				got = append(got, string(b))
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("readRecords() failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readRecords() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkReadRecords_Regexp(b *testing.B) {
+	input := strings.Repeat("abc;", 500000)
+	split := scanRegexp(regexp.MustCompile(`;`))
+	for b.Loop() {
+		err := readRecords(strings.NewReader(input), split, func([]byte) error {
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReadRecords_Paragraph(b *testing.B) {
+	input := strings.Repeat("a\nb\n\n", 50000)
+	for b.Loop() {
+		err := readRecords(strings.NewReader(input), scanParagraph, func([]byte) error {
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestFoldRecords(t *testing.T) {
+	input := "preamble\n" +
+		"2025-10-17 12:00:00 ERROR failed\n" +
+		"java.lang.NullPointerException: oops\n" +
+		"\tat Main.main(Main.java:3)\n" +
+		"2025-10-17 12:00:01 INFO ok\n"
+	want := []string{
+		"preamble",
+		"2025-10-17 12:00:00 ERROR failed\n" +
+			"java.lang.NullPointerException: oops\n" +
+			"\tat Main.main(Main.java:3)",
+		"2025-10-17 12:00:01 INFO ok",
+	}
+
+	read := foldRecords(readLines, regexp.MustCompile(`^\d{4}-\d{2}-\d{2} `))
+
+	var got []string
+	err := read(strings.NewReader(input), func(b []byte) error {
+		got = append(got, string(b))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("foldRecords() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("foldRecords() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Printer writes the selected fields of each record to the output
+type Printer interface {
+	// Header is called with the selected column names of the header of each
+	// input file
+	Header(names []string) error
+	// Print writes the selected fields of a single record
+	Print(fields []string) error
+	// Close writes anything that is required to complete the output
+	Close() error
+}
+
+// NewPrinter returns a Printer for the named output format
+func NewPrinter(w io.Writer, output string) (Printer, error) {
+	switch output {
+	case "plain":
+		return &plainPrinter{w: w}, nil
+	case "json":
+		return newJSONPrinter(w, false), nil
+	case "ndjson":
+		return newJSONPrinter(w, true), nil
+	case "csv":
+		return newCSVPrinter(w, ','), nil
+	case "tsv":
+		return newCSVPrinter(w, '\t'), nil
+	default:
+		return nil, fmt.Errorf("unknown output format: %q", output)
+	}
+}
+
+// outputFormats is the list of formats accepted by NewPrinter
+var outputFormats = []string{"plain", "json", "ndjson", "csv", "tsv"}
+
+// plainPrinter writes fields separated by the output delimiter
+type plainPrinter struct {
+	w             io.Writer
+	headerPrinted bool
+}
+
+var _ Printer = (*plainPrinter)(nil)
+
+func (p *plainPrinter) Header(names []string) error {
+	if p.headerPrinted {
+		return nil
+	}
+	p.headerPrinted = true
+	return p.Print(names)
+}
+
+func (p *plainPrinter) Print(fields []string) error {
+	if _, err := writeFields(p.w, fields); err != nil {
+		return err
+	}
+	_, err := io.WriteString(p.w, string(outputTerm))
+	return err
+}
+
+func (p *plainPrinter) Close() error { return nil }
+
+// csvPrinter writes fields as RFC 4180 records terminated by the output
+// terminator
+type csvPrinter struct {
+	w             io.Writer
+	buf           *bytes.Buffer
+	cw            *csv.Writer
+	headerPrinted bool
+}
+
+var _ Printer = (*csvPrinter)(nil)
+
+func newCSVPrinter(w io.Writer, comma rune) *csvPrinter {
+	buf := bytes.NewBuffer(nil)
+	cw := csv.NewWriter(buf)
+	cw.Comma = comma
+	return &csvPrinter{w: w, buf: buf, cw: cw}
+}
+
+func (p *csvPrinter) Header(names []string) error {
+	if p.headerPrinted {
+		return nil
+	}
+	p.headerPrinted = true
+	return p.Print(names)
+}
+
+func (p *csvPrinter) Print(fields []string) error {
+	p.buf.Reset()
+	if err := p.cw.Write(fields); err != nil {
+		return err
+	}
+	p.cw.Flush()
+	if err := p.cw.Error(); err != nil {
+		return err
+	}
+
+	// the csv writer always terminates records with a newline
+	p.buf.Truncate(p.buf.Len() - 1)
+	p.buf.WriteString(string(outputTerm))
+	_, err := p.w.Write(p.buf.Bytes())
+	return err
+}
+
+func (p *csvPrinter) Close() error { return nil }
+
+// jsonPrinter writes each record as an array of fields, or as an object keyed
+// by the column names when a header is available. The records are either
+// written as a single array or one record per line, terminated by the output
+// terminator.
+type jsonPrinter struct {
+	w     io.Writer
+	buf   *bytes.Buffer
+	enc   *json.Encoder
+	names []string
+	lines bool
+	count int
+}
+
+var _ Printer = (*jsonPrinter)(nil)
+
+func newJSONPrinter(w io.Writer, lines bool) *jsonPrinter {
+	buf := bytes.NewBuffer(nil)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	return &jsonPrinter{w: w, buf: buf, enc: enc, lines: lines}
+}
+
+func (p *jsonPrinter) Header(names []string) error {
+	p.names = names
+	return nil
+}
+
+func (p *jsonPrinter) Print(fields []string) error {
+	p.buf.Reset()
+
+	switch {
+	case p.lines:
+	case p.count == 0:
+		p.buf.WriteString("[\n  ")
+	default:
+		p.buf.WriteString(",\n  ")
+	}
+	p.count++
+
+	if p.names == nil {
+		p.buf.WriteByte('[')
+		for i, field := range fields {
+			if i > 0 {
+				p.buf.WriteByte(',')
+			}
+			p.encode(field)
+		}
+		p.buf.WriteByte(']')
+	} else {
+		p.buf.WriteByte('{')
+		for i, field := range fields {
+			if i > 0 {
+				p.buf.WriteByte(',')
+			}
+			// fields without a column name are keyed by their position
+			if i < len(p.names) {
+				p.encode(p.names[i])
+			} else {
+				p.encode(strconv.Itoa(i + 1))
+			}
+			p.buf.WriteByte(':')
+			p.encode(field)
+		}
+		p.buf.WriteByte('}')
+	}
+
+	if p.lines {
+		p.buf.WriteString(string(outputTerm))
+	}
+
+	_, err := p.w.Write(p.buf.Bytes())
+	return err
+}
+
+// encode writes s as a JSON string to the buffer
+func (p *jsonPrinter) encode(s string) {
+	p.enc.Encode(s)
+	p.buf.Truncate(p.buf.Len() - 1) // remove the newline added by Encode
+}
+
+func (p *jsonPrinter) Close() error {
+	if p.lines {
+		return nil
+	}
+	if p.count == 0 {
+		_, err := io.WriteString(p.w, "[]\n")
+		return err
+	}
+	_, err := io.WriteString(p.w, "\n]\n")
+	return err
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrinter(t *testing.T) {
+	records := [][]string{
+		{"root", `say "hi"`},
+		{"nadim", "a, <b>"},
+	}
+
+	tests := []struct {
+		output string
+		header []string
+		term   escapedValue
+		want   string
+	}{
+		{
+			output: "plain",
+			want:   "root say \"hi\"\nnadim a, <b>\n",
+		},
+		{
+			output: "plain",
+			header: []string{"USER", "NOTE"},
+			want:   "USER NOTE\nroot say \"hi\"\nnadim a, <b>\n",
+		},
+		{
+			output: "json",
+			want:   "[\n  [\"root\",\"say \\\"hi\\\"\"],\n  [\"nadim\",\"a, <b>\"]\n]\n",
+		},
+		{
+			output: "ndjson",
+			header: []string{"USER", "NOTE"},
+			want: "{\"USER\":\"root\",\"NOTE\":\"say \\\"hi\\\"\"}\n" +
+				"{\"USER\":\"nadim\",\"NOTE\":\"a, <b>\"}\n",
+		},
+		{
+			output: "ndjson",
+			header: []string{"USER"},
+			want: "{\"USER\":\"root\",\"2\":\"say \\\"hi\\\"\"}\n" +
+				"{\"USER\":\"nadim\",\"2\":\"a, <b>\"}\n",
+		},
+		{
+			output: "csv",
+			header: []string{"USER", "NOTE"},
+			want:   "USER,NOTE\nroot,\"say \"\"hi\"\"\"\nnadim,\"a, <b>\"\n",
+		},
+		{
+			output: "tsv",
+			want:   "root\t\"say \"\"hi\"\"\"\nnadim\ta, <b>\n",
+		},
+		{
+			output: "csv",
+			term:   "\x00",
+			want:   "root,\"say \"\"hi\"\"\"\x00nadim,\"a, <b>\"\x00",
+		},
+		{
+			output: "ndjson",
+			term:   "\x00",
+			want:   "[\"root\",\"say \\\"hi\\\"\"]\x00[\"nadim\",\"a, <b>\"]\x00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.output, func(t *testing.T) {
+			if tt.term != "" {
+				defer func(term escapedValue) { outputTerm = term }(outputTerm)
+				outputTerm = tt.term
+			}
+
+			buf := bytes.NewBuffer(nil)
+			p, err := NewPrinter(buf, tt.output)
+			if err != nil {
+				t.Fatalf("NewPrinter(%q) failed: %v", tt.output, err)
+			}
+
+			if tt.header != nil {
+				// the header of every file is reported but printed once
+				for range 2 {
+					if err := p.Header(tt.header); err != nil {
+						t.Fatalf("Header() failed: %v", err)
+					}
+				}
+			}
+			for _, r := range records {
+				if err := p.Print(r); err != nil {
+					t.Fatalf("Print() failed: %v", err)
+				}
+			}
+			if err := p.Close(); err != nil {
+				t.Fatalf("Close() failed: %v", err)
+			}
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewPrinter(nil, "xml"); err == nil {
+		t.Errorf("NewPrinter(%q) succeeded, want error", "xml")
+	}
+}
@@ -7,68 +7,234 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+
+	"github.com/rivo/uniseg"
 )
 
 // Range is data type the indicates a range of item for a string slice
 type Range struct {
 	Reversed   bool
 	Exact      bool
+	Exclude    bool
 	Start, End int
+	// Step is the distance between selected items, zero is treated as one
+	Step int
+	// Chars selects the characters of each selected item
+	Chars *Range
+	// Bytes makes Chars select bytes instead of grapheme clusters
+	Bytes bool
 }
 
 // ParseRange parses a string into a Range
 func ParseRange(str string, reversed bool) (*Range, error) {
+	return ParseRangeHeader(str, reversed, nil)
+}
+
+// ParseRangeHeader parses a string into a Range like ParseRange. Additionally,
+// column names from header can be used in place of any index.
+func ParseRangeHeader(str string, reversed bool, header []string) (*Range, error) {
 	if str == "" {
 		return nil, errors.New("empty range string")
 	}
 
+	// An exclusion is a range prefixed with '!'
+	if rest, ok := strings.CutPrefix(str, "!"); ok && !slices.Contains(header, str) {
+		r, err := ParseRangeHeader(rest, reversed, header)
+		if err != nil {
+			return nil, err
+		}
+		if r.Chars != nil {
+			return nil, fmt.Errorf("cannot slice excluded range: %q", str)
+		}
+		r.Exclude = true
+		return r, nil
+	}
+
+	// Characters of the selected items are sliced with a range in brackets
+	if i := strings.IndexByte(str, '['); i > 0 && strings.HasSuffix(str, "]") &&
+		!slices.Contains(header, str) {
+		chars, err := ParseRange(str[i+1:len(str)-1], false)
+		if err != nil {
+			return nil, err
+		}
+		if chars.Chars != nil {
+			return nil, fmt.Errorf("invalid range string: %q", str)
+		}
+		r, err := ParseRangeHeader(str[:i], reversed, header)
+		if err != nil {
+			return nil, err
+		}
+		r.Chars = chars
+		return r, nil
+	}
+
 	if str == ":" {
 		return &Range{Reversed: reversed, Start: 0, End: math.MaxInt}, nil
 	}
 
-	// Check for exact values (no operators)
-	if !strings.ContainsRune(str, ':') {
-		start, err := strconv.Atoi(str)
+	// Check for exact values (no operators). A column name may contain ':'
+	if !strings.ContainsRune(str, ':') || slices.Contains(header, str) {
+		start, err := parseIndex(str, header)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse range: %v", err)
+			return nil, err
 		}
 		return &Range{Exact: true, Start: start, Reversed: reversed}, nil
 	}
 
 	parts := strings.Split(str, ":")
-	if len(parts) != 2 {
+	if len(parts) != 2 && len(parts) != 3 {
 		return nil, fmt.Errorf("invalid range string: %q", str)
 	}
 
+	step := 1
+	if len(parts) == 3 && parts[2] != "" {
+		n, err := strconv.Atoi(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse range step: %v", err)
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("range step cannot be zero: %q", str)
+		}
+		step = n
+	}
+
+	// A negative step walks from the end to the start of the range
+	if step < 0 {
+		parts[0], parts[1] = parts[1], parts[0]
+		reversed = !reversed
+		step = -step
+	}
+
 	start := 0
 	end := math.MaxInt
 
 	if parts[0] != "" {
-		n, err := strconv.Atoi(parts[0])
+		n, err := parseIndex(parts[0], header)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse range: %v", err)
+			return nil, err
 		}
 		start = n
 	}
 
 	if parts[1] != "" {
-		n, err := strconv.Atoi(parts[1])
+		n, err := parseIndex(parts[1], header)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse range: %v", err)
+			return nil, err
 		}
 		end = n
 	}
 
-	return &Range{Start: start, End: end, Reversed: reversed}, nil
+	return &Range{Start: start, End: end, Step: step, Reversed: reversed}, nil
+}
+
+// parseIndex parses an integer index or, when it is not an integer, the
+// 1-based index of the column name in header
+func parseIndex(str string, header []string) (int, error) {
+	n, err := strconv.Atoi(str)
+	if err == nil {
+		return n, nil
+	}
+	if header == nil {
+		return 0, fmt.Errorf("failed to parse range: %v", err)
+	}
+	if i := slices.Index(header, str); i >= 0 {
+		return i + 1, nil
+	}
+	return 0, fmt.Errorf("unknown column: %q", str)
 }
 
 // Select selects item of a []string according to the bound
 func (r *Range) Select(s []string) []string {
-	length := len(s)
-	if length == 0 {
+	selected := r.selectItems(s)
+	if r.Chars == nil {
+		return selected
+	}
+
+	sliced := make([]string, len(selected))
+	for i, str := range selected {
+		sliced[i] = r.sliceChars(str)
+	}
+	return sliced
+}
+
+// sliceChars returns the characters of s selected by the Chars range
+func (r *Range) sliceChars(s string) string {
+	if r.Chars == nil {
+		return s
+	}
+
+	if r.Bytes {
+		var sb strings.Builder
+		for idx := range slices.Values(Ranges{r.Chars}.indices(len(s))) {
+			sb.WriteByte(s[idx])
+		}
+		return sb.String()
+	}
+
+	graphemes := make([]string, 0, len(s))
+	state := -1
+	for rest := s; rest != ""; {
+		var cluster string
+		cluster, rest, _, state = uniseg.FirstGraphemeClusterInString(rest, state)
+		graphemes = append(graphemes, cluster)
+	}
+	return strings.Join(Ranges{r.Chars}.Select(graphemes), "")
+}
+
+// selectItems selects item of a []string according to the bound without
+// slicing their characters
+func (r *Range) selectItems(s []string) []string {
+	start, end, ok := r.bounds(len(s))
+	if !ok {
+		return nil
+	}
+
+	if r.Step > 1 {
+		indices := r.indices(len(s))
+		selected := make([]string, len(indices))
+		for i, idx := range indices {
+			selected[i] = s[idx]
+		}
+		return selected
+	}
+
+	if r.Reversed {
+		sliced := slices.Clone(s[start : end+1])
+		slices.Reverse(sliced)
+		return sliced
+	}
+
+	return s[start : end+1]
+}
+
+// indices returns the 0-based indices selected from a slice of length
+func (r *Range) indices(length int) []int {
+	start, end, ok := r.bounds(length)
+	if !ok {
 		return nil
 	}
 
+	step := max(r.Step, 1)
+	indices := make([]int, 0, (end-start)/step+1)
+	if r.Reversed {
+		for i := end; i >= start; i -= step {
+			indices = append(indices, i)
+		}
+	} else {
+		for i := start; i <= end; i += step {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// bounds returns the inclusive 0-based bounds of the range for a slice of
+// length. It returns false if the range selects nothing.
+func (r *Range) bounds(length int) (int, int, bool) {
+	if length == 0 {
+		return 0, 0, false
+	}
+
 	// Handle exact selection
 	if r.Exact {
 		idx := r.Start
@@ -80,10 +246,10 @@ func (r *Range) Select(s []string) []string {
 
 		// idx == 0 remains 0 (first element)
 		if idx < 0 || idx >= length {
-			return nil
+			return 0, 0, false
 		}
 
-		return []string{s[idx]}
+		return idx, idx, true
 	}
 
 	start, end := r.Start, r.End
@@ -101,17 +267,167 @@ func (r *Range) Select(s []string) []string {
 	}
 
 	if start > end || start >= length {
-		return nil
+		return 0, 0, false
 	}
 
 	start = max(0, min(start, length-1))
 	end = max(0, min(end, length-1))
 
-	if r.Reversed {
-		sliced := slices.Clone(s[start : end+1])
-		slices.Reverse(sliced)
-		return sliced
+	return start, end, true
+}
+
+// Ranges is a list of ranges which are selected together
+type Ranges []*Range
+
+// Select selects items of a []string chosen by the ranges as one ordered set,
+// in the order of the ranges. An item chosen by more than one range is only
+// selected the first time, unless its characters are sliced. Items chosen by
+// an exclusion range are removed from the result. If there are only exclusion
+// ranges, all the remaining items are selected.
+func (rs Ranges) Select(s []string) []string {
+	if len(rs) == 0 {
+		return nil
+	}
+	if len(rs) == 1 && !rs[0].Exclude {
+		// the items may be shared with s, appending must not overwrite it
+		return slices.Clip(rs[0].Select(s))
 	}
 
-	return s[start : end+1]
+	selected := make([]string, 0, len(s))
+	rs.each(len(s), func(r *Range, idx int) {
+		selected = append(selected, r.sliceChars(s[idx]))
+	})
+	return selected
+}
+
+// Complement selects items of a []string, in order, which are not selected by
+// the ranges.
+func (rs Ranges) Complement(s []string) []string {
+	chosen := make([]bool, len(s))
+	for idx := range slices.Values(rs.indices(len(s))) {
+		chosen[idx] = true
+	}
+
+	selected := make([]string, 0, len(s))
+	for i, str := range s {
+		if !chosen[i] {
+			selected = append(selected, str)
+		}
+	}
+	return selected
+}
+
+// ColumnSelector selects the same columns from every record, as chosen by
+// ranges from the columns of a header. A cell missing from a short record is
+// selected as an empty string, so the selected values always line up with the
+// selected columns.
+type ColumnSelector struct {
+	indices []int
+	ranges  []*Range
+}
+
+// NewColumnSelector returns a ColumnSelector for the columns chosen by rs from
+// a header of length, or all the other columns if complement is true
+func NewColumnSelector(rs Ranges, length int, complement bool) *ColumnSelector {
+	c := &ColumnSelector{}
+	if complement {
+		chosen := make([]bool, length)
+		for idx := range slices.Values(rs.indices(length)) {
+			chosen[idx] = true
+		}
+		for idx := range length {
+			if !chosen[idx] {
+				c.indices = append(c.indices, idx)
+				c.ranges = append(c.ranges, nil)
+			}
+		}
+		return c
+	}
+
+	if len(rs) == 0 {
+		return c
+	}
+	rs.each(length, func(r *Range, idx int) {
+		c.indices = append(c.indices, idx)
+		c.ranges = append(c.ranges, r)
+	})
+	return c
+}
+
+// Names returns the names of the columns from the header. Unlike Select, the
+// characters of the names are not sliced.
+func (c *ColumnSelector) Names(header []string) []string {
+	names := make([]string, len(c.indices))
+	for i, idx := range c.indices {
+		names[i] = header[idx]
+	}
+	return names
+}
+
+// Select selects the columns from the fields of a record. A record without any
+// of the columns selects nothing.
+func (c *ColumnSelector) Select(fields []string) []string {
+	if !slices.ContainsFunc(c.indices, func(idx int) bool { return idx < len(fields) }) {
+		return nil
+	}
+
+	selected := make([]string, len(c.indices))
+	for i, idx := range c.indices {
+		if idx >= len(fields) {
+			continue
+		}
+		if r := c.ranges[i]; r != nil {
+			selected[i] = r.sliceChars(fields[idx])
+		} else {
+			selected[i] = fields[idx]
+		}
+	}
+	return selected
+}
+
+// indices returns the 0-based indices selected from a slice of length
+func (rs Ranges) indices(length int) []int {
+	indices := make([]int, 0, length)
+	rs.each(length, func(_ *Range, idx int) {
+		indices = append(indices, idx)
+	})
+	return indices
+}
+
+// each calls fn with each of the ranges, which are not exclusions, and every
+// 0-based index it selects from a slice of length that is not excluded. An
+// index is skipped if it was already selected whole by a previous range.
+func (rs Ranges) each(length int, fn func(r *Range, idx int)) {
+	excluded := make([]bool, length)
+	selected := make([]bool, length)
+	included := make(Ranges, 0, len(rs))
+	for r := range slices.Values(rs) {
+		if !r.Exclude {
+			included = append(included, r)
+			continue
+		}
+		for idx := range slices.Values(r.indices(length)) {
+			excluded[idx] = true
+		}
+	}
+
+	if len(included) == 0 {
+		included = Ranges{{Start: 0, End: math.MaxInt}}
+	}
+
+	for r := range slices.Values(included) {
+		for idx := range slices.Values(r.indices(length)) {
+			if excluded[idx] {
+				continue
+			}
+			// sliced characters are a part of the item, not the whole item
+			if r.Chars == nil {
+				if selected[idx] {
+					continue
+				}
+				selected[idx] = true
+			}
+			fn(r, idx)
+		}
+	}
 }
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -397,3 +398,289 @@ func equalSlices(a, b []string) bool {
 	}
 	return true
 }
+
+func TestParseRangeHeader(t *testing.T) {
+	header := []string{"USER", "PID", "%CPU", "TIME:", "COMMAND"}
+	testSlice := []string{"root", "1", "0.0", "0:01", "/sbin/init splash"}
+
+	tests := []struct {
+		rangeStr string
+		want     []string
+	}{
+		{"USER", []string{"root"}},
+		{"COMMAND", []string{"/sbin/init splash"}},
+		{"TIME:", []string{"0:01"}},
+		{"PID:%CPU", []string{"1", "0.0"}},
+		{"%CPU:", []string{"0.0", "0:01", "/sbin/init splash"}},
+		{":PID", []string{"root", "1"}},
+		{"2:USER", nil},
+		{"2", []string{"1"}},
+		{"-1", []string{"/sbin/init splash"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rangeStr, func(t *testing.T) {
+			r, err := ParseRangeHeader(tt.rangeStr, false, header)
+			if err != nil {
+				t.Fatalf("ParseRangeHeader(%q) failed: %v", tt.rangeStr, err)
+			}
+
+			got := r.Select(testSlice)
+			if !equalSlices(got, tt.want) {
+				t.Errorf("Select() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, str := range []string{"NAME", "USER:NAME", "user"} {
+		if _, err := ParseRangeHeader(str, false, header); err == nil {
+			t.Errorf("ParseRangeHeader(%q) succeeded, want error", str)
+		}
+	}
+}
+
+func TestRanges_Select(t *testing.T) {
+	testSlice := []string{"a", "b", "c", "d", "e"}
+
+	tests := []struct {
+		name           string
+		args           []string
+		want           []string
+		wantComplement []string
+	}{
+		{
+			name:           "ranges are combined in order",
+			args:           []string{"3", "1:2", "1"},
+			want:           []string{"c", "a", "b"},
+			wantComplement: []string{"d", "e"},
+		},
+		{
+			name:           "single exclusion selects everything else",
+			args:           []string{"!1"},
+			want:           []string{"b", "c", "d", "e"},
+			wantComplement: []string{"a"},
+		},
+		{
+			name:           "exclusion range",
+			args:           []string{"!2:4"},
+			want:           []string{"a", "e"},
+			wantComplement: []string{"b", "c", "d"},
+		},
+		{
+			name:           "exclusion with negative index",
+			args:           []string{"!-1", "!1"},
+			want:           []string{"b", "c", "d"},
+			wantComplement: []string{"a", "e"},
+		},
+		{
+			name:           "exclusion removes from included ranges",
+			args:           []string{"5", "1:4", "!2"},
+			want:           []string{"e", "a", "c", "d"},
+			wantComplement: []string{"b"},
+		},
+		{
+			name:           "exclusion keeps order of included ranges",
+			args:           []string{"-1:", ":", "!3"},
+			want:           []string{"e", "a", "b", "d"},
+			wantComplement: []string{"c"},
+		},
+		{
+			name:           "overlapping ranges select items once",
+			args:           []string{"1:3", "2", "-3:"},
+			want:           []string{"a", "b", "c", "d", "e"},
+			wantComplement: []string{},
+		},
+		{
+			name:           "sliced characters are selected again",
+			args:           []string{"1", "1[:1]", "2[2:]"},
+			want:           []string{"a", "a", ""},
+			wantComplement: []string{"c", "d", "e"},
+		},
+		{
+			name:           "exclusion out of bounds",
+			args:           []string{"!10"},
+			want:           []string{"a", "b", "c", "d", "e"},
+			wantComplement: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranges := make(Ranges, len(tt.args))
+			for i, a := range tt.args {
+				r, err := ParseRange(a, false)
+				if err != nil {
+					t.Fatalf("ParseRange(%q) failed: %v", a, err)
+				}
+				ranges[i] = r
+			}
+
+			if got := ranges.Select(testSlice); !equalSlices(got, tt.want) {
+				t.Errorf("Select() = %v, want %v", got, tt.want)
+			}
+
+			got := ranges.Complement(testSlice)
+			if !equalSlices(got, tt.wantComplement) {
+				t.Errorf("Complement() = %v, want %v", got, tt.wantComplement)
+			}
+		})
+	}
+}
+
+func TestColumnSelector(t *testing.T) {
+	header := []string{"a", "b", "c", "d"}
+
+	tests := []struct {
+		name       string
+		args       []string
+		complement bool
+		fields     []string
+		want       []string
+		names      []string
+	}{
+		{
+			name:   "short record keeps the columns in place",
+			args:   []string{"c", "a"},
+			fields: []string{"x", "y"},
+			want:   []string{"", "x"},
+			names:  []string{"c", "a"},
+		},
+		{
+			name:   "full record",
+			args:   []string{"c", "a"},
+			fields: []string{"w", "x", "y", "z"},
+			want:   []string{"y", "w"},
+			names:  []string{"c", "a"},
+		},
+		{
+			name:   "ranges are resolved against the header",
+			args:   []string{"-1", "2"},
+			fields: []string{"w", "x", "y"},
+			want:   []string{"", "x"},
+			names:  []string{"d", "b"},
+		},
+		{
+			name:   "sliced characters",
+			args:   []string{"b[:1]", "!c"},
+			fields: []string{"w", "xyz"},
+			want:   []string{"x"},
+			names:  []string{"b"},
+		},
+		{
+			name:       "complement",
+			args:       []string{"b"},
+			complement: true,
+			fields:     []string{"w", "x", "y"},
+			want:       []string{"w", "y", ""},
+			names:      []string{"a", "c", "d"},
+		},
+		{
+			name:   "record without the columns",
+			args:   []string{"d"},
+			fields: []string{"w"},
+			want:   nil,
+			names:  []string{"d"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranges, err := parseRanges(tt.args, header)
+			if err != nil {
+				t.Fatalf("parseRanges(%q) failed: %v", tt.args, err)
+			}
+			c := NewColumnSelector(ranges, len(header), tt.complement)
+			if got := c.Select(tt.fields); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Select(%q) = %q, want %q", tt.fields, got, tt.want)
+			}
+			if got := c.Names(header); !reflect.DeepEqual(got, tt.names) {
+				t.Errorf("Names() = %q, want %q", got, tt.names)
+			}
+		})
+	}
+}
+
+func TestRange_Select_Step(t *testing.T) {
+	testSlice := []string{"a", "b", "c", "d", "e"}
+
+	tests := []struct {
+		rangeStr string
+		want     []string
+	}{
+		{"1::2", []string{"a", "c", "e"}},
+		{"2::2", []string{"b", "d"}},
+		{"::3", []string{"a", "d"}},
+		{"1:4:2", []string{"a", "c"}},
+		{"1:5:", []string{"a", "b", "c", "d", "e"}},
+		{"::-1", []string{"e", "d", "c", "b", "a"}},
+		{"::-2", []string{"e", "c", "a"}},
+		{"4:2:-1", []string{"d", "c", "b"}},
+		{"-1:1:-2", []string{"e", "c", "a"}},
+		{"2:4:-1", nil},
+		{"10:20:2", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rangeStr, func(t *testing.T) {
+			r, err := ParseRange(tt.rangeStr, false)
+			if err != nil {
+				t.Fatalf("ParseRange(%q) failed: %v", tt.rangeStr, err)
+			}
+
+			got := r.Select(testSlice)
+			if !equalSlices(got, tt.want) {
+				t.Errorf("Select() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, str := range []string{"1:5:0", "1:5:x", "1:2:3:4"} {
+		if _, err := ParseRange(str, false); err == nil {
+			t.Errorf("ParseRange(%q) succeeded, want error", str)
+		}
+	}
+}
+
+func TestRange_Select_Chars(t *testing.T) {
+	testSlice := []string{"0123abcdef", "2025-10-17T12:00:00Z", "héllo 👍🏽!"}
+
+	tests := []struct {
+		rangeStr string
+		bytes    bool
+		want     []string
+	}{
+		{"1[1:4]", false, []string{"0123"}},
+		{"1[:8]", false, []string{"0123abcd"}},
+		{"2[:10]", false, []string{"2025-10-17"}},
+		{"1[-3:]", false, []string{"def"}},
+		{"1[::-1]", false, []string{"fedcba3210"}},
+		{"1[!1:4]", false, []string{"abcdef"}},
+		{"1[20]", false, []string{""}},
+		{"3[2]", false, []string{"é"}},
+		{"3[-2]", false, []string{"👍🏽"}},
+		{"3[2:3]", true, []string{"é"}},
+		{"3[2]", true, []string{"\xc3"}},
+		{"1:2[1:4]", false, []string{"0123", "2025"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rangeStr, func(t *testing.T) {
+			r, err := ParseRange(tt.rangeStr, false)
+			if err != nil {
+				t.Fatalf("ParseRange(%q) failed: %v", tt.rangeStr, err)
+			}
+			r.Bytes = tt.bytes
+
+			got := r.Select(testSlice)
+			if !equalSlices(got, tt.want) {
+				t.Errorf("Select() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	for _, str := range []string{"1[x]", "!1[1:2]", "1[1[2]]"} {
+		if _, err := ParseRange(str, false); err == nil {
+			t.Errorf("ParseRange(%q) succeeded, want error", str)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/carapace-sh/carapace"
+	shlex "github.com/carapace-sh/carapace-shlex"
+	"github.com/spf13/cobra"
+)
+
+// Splitter splits a single input record into at most n fields. If n < 0, it
+// returns all fields.
+type Splitter func(b []byte, n int) ([]string, error)
+
+// NewSplitter returns the Splitter selected by the flags of cmd
+func NewSplitter(cmd *cobra.Command) (Splitter, error) {
+	flags := cmd.Flags()
+
+	switch {
+	case shell:
+		return func(b []byte, _ int) ([]string, error) {
+			s, err := shlex.Split(string(b))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse qouted field: %w", err)
+			}
+			return s.Strings(), nil
+		}, nil
+	case flags.Changed("regex-delimiter"):
+		re, err := regexp.Compile(regexDelimiter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex delimiter: %w", err)
+		}
+		if keepEmpty {
+			return func(b []byte, n int) ([]string, error) {
+				return SplitNRegexp(b, re, n), nil
+			}, nil
+		}
+		return func(b []byte, n int) ([]string, error) {
+			return FieldNRegexp(b, re, n), nil
+		}, nil
+	case flags.Changed("widths"):
+		w, err := parseWidths(widths)
+		if err != nil {
+			return nil, err
+		}
+		return func(b []byte, _ int) ([]string, error) {
+			return FieldWidths(b, w), nil
+		}, nil
+	case flags.Changed("delimiter"):
+		delims, err := parseDelimiters(delimiters)
+		if err != nil {
+			return nil, err
+		}
+		if delims != nil {
+			return delimiterSplitter(delims), nil
+		}
+		return whitespaceSplitter(), nil
+	case paragraph:
+		// each line of a paragraph is a field
+		return delimiterSplitter([]string{"\n"}), nil
+	}
+
+	return whitespaceSplitter(), nil
+}
+
+// whitespaceSplitter returns a Splitter which splits on any whitespace
+func whitespaceSplitter() Splitter {
+	if keepEmpty {
+		return func(b []byte, n int) ([]string, error) {
+			return SplitNFunc(b, unicode.IsSpace, n), nil
+		}
+	}
+	return func(b []byte, n int) ([]string, error) {
+		return FieldNFunc(b, unicode.IsSpace, n), nil
+	}
+}
+
+// parseWidths parses a comma separated list of column widths. The last width
+// may be '*' to take the remainder of the line, which is returned as -1.
+func parseWidths(spec string) ([]int, error) {
+	parts := strings.Split(spec, ",")
+	result := make([]int, len(parts))
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "*" && i == len(parts)-1 {
+			result[i] = -1
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid column width: %q", part)
+		}
+		result[i] = n
+	}
+	return result, nil
+}
+
+// delimiterSplitter returns a Splitter for one or more literal delimiters
+func delimiterSplitter(delims []string) Splitter {
+	switch {
+	case len(delims) == 1 && keepEmpty:
+		return func(b []byte, n int) ([]string, error) {
+			return SplitN(b, delims[0], n), nil
+		}
+	case len(delims) == 1:
+		return func(b []byte, n int) ([]string, error) {
+			return FieldN(b, delims[0], n), nil
+		}
+	case keepEmpty:
+		return func(b []byte, n int) ([]string, error) {
+			return SplitNAny(b, delims, n), nil
+		}
+	default:
+		return func(b []byte, n int) ([]string, error) {
+			return FieldNAny(b, delims, n), nil
+		}
+	}
+}
+
+// whitespacePreset is the name of the delimiter preset which splits on any
+// whitespace character
+const whitespacePreset = "whitespace"
+
+// delimiterPresets are the named delimiters accepted by parseDelimiters
+var delimiterPresets = map[string]string{
+	"space":            " ",
+	"tab":              "\t",
+	"comma":            ",",
+	"colon":            ":",
+	"semicolon":        ";",
+	"pipe":             "|",
+	"nul":              "\x00",
+	"unit-separator":   "\x1f",
+	"record-separator": "\x1e",
+}
+
+// delimiterCompletion completes the names of the delimiter presets
+func delimiterCompletion() carapace.Action {
+	values := []string{whitespacePreset, "any whitespace character"}
+	for name := range maps.Keys(delimiterPresets) {
+		values = append(values, name, strconv.Quote(delimiterPresets[name]))
+	}
+	return carapace.ActionValuesDescribed(values...)
+}
+
+// parseDelimiters parses the delimiter specs into a list of delimiters. A spec
+// is either a named preset, a set of single character delimiters like "[,;|]"
+// or a literal delimiter. Backslash escapes are processed in sets and literal
+// delimiters. It returns nil if the only spec is the whitespace preset.
+func parseDelimiters(specs []string) ([]string, error) {
+	if slices.Contains(specs, whitespacePreset) {
+		if len(specs) > 1 {
+			return nil, errors.New(
+				"whitespace delimiter cannot be combined with other delimiters",
+			)
+		}
+		return nil, nil
+	}
+
+	delims := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		if preset, ok := delimiterPresets[spec]; ok {
+			delims = append(delims, preset)
+			continue
+		}
+
+		if len(spec) > 2 && spec[0] == '[' && spec[len(spec)-1] == ']' {
+			set := unescape(spec[1 : len(spec)-1])
+			for i := 0; i < len(set); {
+				_, size := utf8.DecodeRuneInString(set[i:])
+				delims = append(delims, set[i:i+size])
+				i += size
+			}
+			continue
+		}
+
+		delim := unescape(spec)
+		if delim == "" {
+			return nil, errors.New("empty delimiter")
+		}
+		delims = append(delims, delim)
+	}
+	return delims, nil
+}
+
+// unescape replaces the backslash escapes of s, like "\t", "\x00" and
+// "\u2502", with the characters they represent. Other backslashes are kept as
+// they are, like in "C:\path".
+func unescape(s string) string {
+	if !strings.ContainsRune(s, '\\') {
+		return s
+	}
+
+	var sb strings.Builder
+	for rest := s; rest != ""; {
+		value, multibyte, tail, err := strconv.UnquoteChar(rest, 0)
+		if err != nil {
+			// only a backslash can start an invalid escape sequence
+			sb.WriteByte(rest[0])
+			rest = rest[1:]
+			continue
+		}
+		if multibyte || value < utf8.RuneSelf {
+			sb.WriteRune(value)
+		} else {
+			sb.WriteByte(byte(value))
+		}
+		rest = tail
+	}
+	return sb.String()
+}
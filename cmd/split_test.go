@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDelimiters(t *testing.T) {
+	tests := []struct {
+		name  string
+		specs []string
+		want  []string
+	}{
+		{"single delimiter", []string{":"}, []string{":"}},
+		{"repeated flag", []string{",", "::"}, []string{",", "::"}},
+		{"character set", []string{"[,;|]"}, []string{",", ";", "|"}},
+		{"unicode character set", []string{"[│|]"}, []string{"│", "|"}},
+		{"brackets are literal", []string{"[]"}, []string{"[]"}},
+		{"named presets", []string{"tab", "pipe"}, []string{"\t", "|"}},
+		{"escape sequences", []string{`\t`, `\x1f`}, []string{"\t", "\x1f"}},
+		{"unicode escape", []string{`\u2502`}, []string{"│"}},
+		{"escaped character set", []string{`[\t\x00]`}, []string{"\t", "\x00"}},
+		{"lone backslash", []string{`\`}, []string{`\`}},
+		{"unknown escapes are literal", []string{`\q`, `\x`}, []string{`\q`, `\x`}},
+		{"whitespace preset", []string{"whitespace"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDelimiters(tt.specs)
+			if err != nil {
+				t.Fatalf("parseDelimiters(%q) failed: %v", tt.specs, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseDelimiters(%q) = %q, want %q",
+					tt.specs, got, tt.want)
+			}
+		})
+	}
+
+	invalid := [][]string{{""}, {"whitespace", ","}}
+	for _, specs := range invalid {
+		if _, err := parseDelimiters(specs); err == nil {
+			t.Errorf("parseDelimiters(%q) succeeded, want error", specs)
+		}
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`a b`, "a b"},
+		{`\t`, "\t"},
+		{`a\nb`, "a\nb"},
+		{`\\`, `\`},
+		{`\x00`, "\x00"},
+		{`\xff`, "\xff"},
+		{`\u2502`, "│"},
+		{`"'`, `"'`},
+		{`\`, `\`},
+		{`a\`, `a\`},
+		{`\"`, `\"`},
+		{`C:\path\n`, "C:\\path\n"},
+		{`\x4`, `\x4`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := unescape(tt.input); got != tt.want {
+				t.Errorf("unescape(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// tableSampleSize is the number of lines after the header used for detecting
+// the columns of a table
+const tableSampleSize = 100
+
+// DetectColumns returns the widths of the columns of a left-aligned table,
+// which can be used with FieldWidths. A column starts wherever a header name
+// starts after a cell that is blank in the header and every line of sample.
+// The sample ends at the first blank line, which usually ends the table. The
+// last column takes the remainder of the line and its width is -1.
+func DetectColumns(header []byte, sample [][]byte) []int {
+	headerCells := blankCells(header)
+	blank := slices.Clone(headerCells)
+	for line := range slices.Values(sample) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			break
+		}
+		cells := blankCells(line)
+		for i := range min(len(blank), len(cells)) {
+			blank[i] = blank[i] && cells[i]
+		}
+	}
+
+	// the indentation before the first name belongs to the first column
+	first := slices.Index(headerCells, false)
+	if first < 0 {
+		return []int{-1}
+	}
+
+	widths := make([]int, 0, minResultSize)
+	start := 0
+	for i := first + 1; i < len(blank); i++ {
+		if blank[i-1] && !headerCells[i] {
+			widths = append(widths, i-start)
+			start = i
+		}
+	}
+	return append(widths, -1)
+}
+
+// blankCells reports for each display cell of s whether it is blank
+func blankCells(s []byte) []bool {
+	cells := make([]bool, 0, len(s))
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRune(s[i:])
+		for range runewidth.RuneWidth(r) {
+			cells = append(cells, unicode.IsSpace(r))
+		}
+		i += size
+	}
+	return cells
+}
+
+// tableReader returns a RecordReader which detects the columns of a table from
+// the header and the leading records, then splits every record read by read at
+// these columns
+func tableReader(read rawReader) RecordReader {
+	return func(r io.Reader, fn func(raw []byte, fields []string) error) error {
+		var widths []int
+		lines := make([][]byte, 0, tableSampleSize+1)
+
+		flush := func() error {
+			widths = DetectColumns(lines[0], lines[1:])
+			for line := range slices.Values(lines) {
+				if err := fn(line, FieldWidths(line, widths)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		err := read(r, func(b []byte) error {
+			if widths != nil {
+				return fn(b, FieldWidths(b, widths))
+			}
+			lines = append(lines, bytes.Clone(b))
+			if len(lines) <= tableSampleSize {
+				return nil
+			}
+			return flush()
+		})
+		if err != nil {
+			return err
+		}
+
+		if widths == nil && len(lines) > 0 {
+			return flush()
+		}
+		return nil
+	}
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectColumns(t *testing.T) {
+	tests := []struct {
+		name  string
+		table string
+		want  []int
+	}{
+		{
+			name: "header names with spaces",
+			table: "CONTAINER ID   IMAGE   NAMES\n" +
+				"a1b2c3d4e5f6   nginx   web\n",
+			want: []int{15, 8, -1},
+		},
+		{
+			name: "single space between columns",
+			table: "  UNIT    LOAD   ACTIVE SUB\n" +
+				"  a.mount loaded active mounted\n" +
+				"  b.mount loaded active mounted\n",
+			want: []int{10, 7, 7, -1},
+		},
+		{
+			name: "cells with spaces",
+			table: "NAME  STATUS      AGE\n" +
+				"web   Up 2 hours  3d\n" +
+				"db    Exited (0)  1h\n",
+			want: []int{6, 12, -1},
+		},
+		{
+			name: "sample ends at blank line",
+			table: "UNIT    LOAD\n" +
+				"a.mount loaded\n" +
+				"\n" +
+				"LOAD = Reflects whether the unit definition was loaded.\n",
+			want: []int{8, -1},
+		},
+		{
+			name:  "header only",
+			table: "USER   PID\n",
+			want:  []int{7, -1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lines [][]byte
+			for line := range strings.Lines(tt.table) {
+				lines = append(lines, []byte(strings.TrimSuffix(line, "\n")))
+			}
+
+			got := DetectColumns(lines[0], lines[1:])
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DetectColumns() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTableReader(t *testing.T) {
+	table := "NAME  STATUS      AGE\n" +
+		"web   Up 2 hours  3d\n" +
+		"db    Exited (0)  1h\n"
+	want := [][]string{
+		{"NAME", "STATUS", "AGE"},
+		{"web", "Up 2 hours", "3d"},
+		{"db", "Exited (0)", "1h"},
+	}
+
+	var got [][]string
+	read := tableReader(readLines)
+	err := read(strings.NewReader(table), func(_ []byte, fields []string) error {
+		got = append(got, fields)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("tableReader() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tableReader() = %q, want %q", got, want)
+	}
+}
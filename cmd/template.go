@@ -0,0 +1,318 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+// templateVariables are the reserved tags of a template which are not ranges
+var templateVariables = []string{"NR", "NF", "LINE", "FILENAME", "FNR"}
+
+// Template is a format like "{1}:{2|upper}" in which each tag is replaced by
+// the fields selected by its range, or by the value of a reserved variable:
+//
+//	NR                 number of the record in all inputs
+//	NF                 number of fields of the record
+//	LINE               unsplit record as read from the input
+//	FILENAME           name of the input file, - for the standard input
+//	FNR                number of the record in the input file
+//
+// The value of a tag can be piped through filters separated by '|', which take
+// arguments separated by ':'. Arguments are bare words or strings quoted with
+// double or single quotes, in which a backslash escapes the next character.
+// The built-in filters are:
+//
+//	upper              convert to upper case
+//	lower              convert to lower case
+//	trim               remove leading and trailing white space
+//	trunc:N            keep at most the first N characters
+//	default:S          replace an empty value with S
+//	replace:OLD:NEW    replace every occurrence of OLD with NEW
+//	base64             encode as standard base64
+//	len                replace with the number of characters
+type Template struct {
+	segments []templateSegment
+}
+
+// templateSegment is either literal text or a tag of a template
+type templateSegment struct {
+	text string
+	tag  *templateTag
+}
+
+// templateTag is a tag of a template with its filters
+type templateTag struct {
+	spec    string
+	ranges  Ranges
+	filters []templateFilter
+}
+
+// templateFilter transforms the value of a tag
+type templateFilter func(s string) string
+
+// templateFilterDef is a built-in filter which takes a number of arguments
+type templateFilterDef struct {
+	args int
+	new  func(args []string) (templateFilter, error)
+}
+
+// templateFilters are the built-in filters of templates
+var templateFilters = map[string]templateFilterDef{
+	"upper": {0, func([]string) (templateFilter, error) {
+		return strings.ToUpper, nil
+	}},
+	"lower": {0, func([]string) (templateFilter, error) {
+		return strings.ToLower, nil
+	}},
+	"trim": {0, func([]string) (templateFilter, error) {
+		return strings.TrimSpace, nil
+	}},
+	"trunc": {1, func(args []string) (templateFilter, error) {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid length for trunc: %q", args[0])
+		}
+		return func(s string) string { return truncate(s, n) }, nil
+	}},
+	"default": {1, func(args []string) (templateFilter, error) {
+		return func(s string) string {
+			if s == "" {
+				return args[0]
+			}
+			return s
+		}, nil
+	}},
+	"replace": {2, func(args []string) (templateFilter, error) {
+		return func(s string) string {
+			return strings.ReplaceAll(s, args[0], args[1])
+		}, nil
+	}},
+	"base64": {0, func([]string) (templateFilter, error) {
+		return func(s string) string {
+			return base64.StdEncoding.EncodeToString([]byte(s))
+		}, nil
+	}},
+	"len": {0, func([]string) (templateFilter, error) {
+		return func(s string) string {
+			return strconv.Itoa(uniseg.GraphemeClusterCount(s))
+		}, nil
+	}},
+}
+
+// truncate returns the first n characters of s
+func truncate(s string, n int) string {
+	state := -1
+	rest := s
+	for range n {
+		if rest == "" {
+			return s
+		}
+		_, rest, _, state = uniseg.FirstGraphemeClusterInString(rest, state)
+	}
+	return s[:len(s)-len(rest)]
+}
+
+// formatEscapes replaces the backslash escapes of the text of a template.
+// Other backslashes are kept as they are, like in "C:\path".
+var formatEscapes = strings.NewReplacer(`\\`, `\`, `\t`, "\t", `\n`, "\n")
+
+// ParseTemplate parses str into a Template with tags delimited by start and
+// end. A doubled delimiter, like "{{", is a literal delimiter and the escapes
+// \t, \n and \\ are processed in the text outside of tags. The filters are
+// checked here, the ranges of the tags are resolved by Bind.
+func ParseTemplate(str, start, end string) (*Template, error) {
+	if start == "" || end == "" {
+		return nil, errors.New("empty format delimiter")
+	}
+
+	t := &Template{}
+	var text strings.Builder
+	flush := func() {
+		if text.Len() == 0 {
+			return
+		}
+		s := formatEscapes.Replace(text.String())
+		t.segments = append(t.segments, templateSegment{text: s})
+		text.Reset()
+	}
+
+	for str != "" {
+		switch {
+		case strings.HasPrefix(str, start+start):
+			text.WriteString(start)
+			str = str[2*len(start):]
+		case strings.HasPrefix(str, end+end):
+			text.WriteString(end)
+			str = str[2*len(end):]
+		case strings.HasPrefix(str, start):
+			flush()
+			str = str[len(start):]
+
+			i := tagEnd(str, end)
+			if i < 0 {
+				return nil, fmt.Errorf("missing %q in format", end)
+			}
+			tag, err := parseTemplateTag(str[:i])
+			if err != nil {
+				return nil, err
+			}
+			t.segments = append(t.segments, templateSegment{tag: tag})
+			str = str[i+len(end):]
+		default:
+			_, size := utf8.DecodeRuneInString(str)
+			text.WriteString(str[:size])
+			str = str[size:]
+		}
+	}
+
+	flush()
+	return t, nil
+}
+
+// tagEnd returns the index of end in str, which is the content of a tag, or -1.
+// Quoted filter arguments may contain end.
+func tagEnd(str, end string) int {
+	pipe := strings.IndexByte(str, '|')
+	if i := strings.Index(str, end); pipe < 0 || (i >= 0 && i < pipe) {
+		return i
+	}
+
+	for i := pipe; i < len(str); i++ {
+		switch {
+		case strings.HasPrefix(str[i:], end):
+			return i
+		case str[i] == '"' || str[i] == '\'':
+			quote := str[i]
+			for i++; i < len(str) && str[i] != quote; i++ {
+				if str[i] == '\\' {
+					i++
+				}
+			}
+		}
+	}
+	return -1
+}
+
+// parseTemplateTag parses the content of a tag, like "2|trunc:40"
+func parseTemplateTag(str string) (*templateTag, error) {
+	spec, pipeline, hasFilters := strings.Cut(str, "|")
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("empty tag in format: %q", str)
+	}
+
+	tag := &templateTag{spec: spec}
+	for hasFilters {
+		words, rest, err := splitFilter(pipeline)
+		if err != nil {
+			return nil, err
+		}
+
+		name := strings.TrimSpace(words[0])
+		def, ok := templateFilters[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown filter in format: %q", name)
+		}
+		if len(words)-1 != def.args {
+			return nil, fmt.Errorf(
+				"filter %s takes %d argument(s), got %d", name, def.args, len(words)-1,
+			)
+		}
+
+		filter, err := def.new(words[1:])
+		if err != nil {
+			return nil, err
+		}
+		tag.filters = append(tag.filters, filter)
+		pipeline, hasFilters = rest, rest != ""
+	}
+	return tag, nil
+}
+
+// splitFilter splits the first filter of a pipeline into its name and the
+// arguments, and returns the rest of the pipeline after the next '|'
+func splitFilter(pipeline string) ([]string, string, error) {
+	var words []string
+	var word strings.Builder
+	for i := 0; i < len(pipeline); i++ {
+		switch c := pipeline[i]; c {
+		case ':':
+			words = append(words, word.String())
+			word.Reset()
+		case '|':
+			if i == len(pipeline)-1 {
+				return nil, "", errors.New("empty filter in format")
+			}
+			return append(words, word.String()), pipeline[i+1:], nil
+		case '"', '\'':
+			for i++; i < len(pipeline) && pipeline[i] != c; i++ {
+				if pipeline[i] == '\\' && i+1 < len(pipeline) {
+					i++
+				}
+				word.WriteByte(pipeline[i])
+			}
+			if i >= len(pipeline) {
+				return nil, "", errors.New("unterminated string in format")
+			}
+		default:
+			word.WriteByte(c)
+		}
+	}
+	return append(words, word.String()), "", nil
+}
+
+// Bind resolves the ranges of the tags with column names from header, which
+// may be nil. Reserved variables are not resolved.
+func (t *Template) Bind(header []string) error {
+	for _, seg := range t.segments {
+		if seg.tag == nil || slices.Contains(templateVariables, seg.tag.spec) {
+			continue
+		}
+		r, err := parseRange(seg.tag.spec, header)
+		if err != nil {
+			return err
+		}
+		seg.tag.ranges = Ranges{r}
+	}
+	return nil
+}
+
+// Execute writes the template to w with the tags replaced by the fields they
+// select separated by the output delimiter. Reserved variables are replaced
+// by the values returned by vars.
+func (t *Template) Execute(
+	w io.Writer, fields []string, vars func(name string) string,
+) error {
+	for _, seg := range t.segments {
+		if seg.tag == nil {
+			if _, err := io.WriteString(w, seg.text); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var value string
+		if seg.tag.ranges == nil {
+			value = vars(seg.tag.spec)
+		} else {
+			selected := seg.tag.ranges.Select(fields)
+			value = strings.Join(selected, string(outputDelim))
+		}
+		for filter := range slices.Values(seg.tag.filters) {
+			value = filter(value)
+		}
+
+		if _, err := io.WriteString(w, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplate_Execute(t *testing.T) {
+	header := []string{"USER", "PID", "COMMAND"}
+	fields := []string{"root", "1", "  /sbin/init splash  ", ""}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"{1}:{2}", "root:1"},
+		{"{USER|upper}", "ROOT"},
+		{"{1|upper|lower}", "root"},
+		{"{3|trim}", "/sbin/init splash"},
+		{"{3|trim|trunc:5}", "/sbin"},
+		{"{1|trunc:10}", "root"},
+		{"{4|default:\"-\"}", "-"},
+		{"{1|default:-}", "root"},
+		{`{3|trim|replace:"/":"_"}`, "_sbin_init splash"},
+		{`{1|replace:'o':"|}"}`, "r|}|}t"},
+		{"{1|base64}", "cm9vdA=="},
+		{"{3|len}", "21"},
+		{"{1:2}", "root 1"},
+		{"[{FILENAME}:{FNR}]", "[-:1]"},
+		{"{NR}/{NF}: {LINE|upper}", "7/4: ROOT 1"},
+		{"no tags", "no tags"},
+		{"{PID|lower} {USER}", "1 root"},
+	}
+
+	vars := func(name string) string {
+		switch name {
+		case "FILENAME":
+			return "-"
+		case "FNR":
+			return "1"
+		case "NR":
+			return "7"
+		case "NF":
+			return "4"
+		case "LINE":
+			return "root 1"
+		}
+		return ""
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			tmpl, err := ParseTemplate(tt.format, "{", "}")
+			if err != nil {
+				t.Fatalf("ParseTemplate(%q) failed: %v", tt.format, err)
+			}
+			if err := tmpl.Bind(header); err != nil {
+				t.Fatalf("Bind() failed: %v", err)
+			}
+
+			var sb strings.Builder
+			if err := tmpl.Execute(&sb, fields, vars); err != nil {
+				t.Fatalf("Execute() failed: %v", err)
+			}
+			if got := sb.String(); got != tt.want {
+				t.Errorf("Execute() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTemplate_Escape(t *testing.T) {
+	fields := []string{"root", "1"}
+
+	tests := []struct {
+		format     string
+		start, end string
+		want       string
+	}{
+		{`{{"user": "{1}"}}`, "{", "}", `{"user": "root"}`},
+		{"{{1}}", "{", "}", "{1}"},
+		{"{{{1}}}", "{", "}", "{root}"},
+		{"a}b", "{", "}", "a}b"},
+		{`{1}\t{2}\n`, "{", "}", "root\t1\n"},
+		{`C:\path {1}\q`, "{", "}", `C:\path root\q`},
+		{`{1}\\t\\`, "{", "}", `root\t\`},
+		{"<<1>>:{2}", "<<", ">>", "root:{2}"},
+		{"<<<<1>>>> <<2|upper>>", "<<", ">>", "<<1>> 1"},
+		{"%1% %%", "%", "%", "root %"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			tmpl, err := ParseTemplate(tt.format, tt.start, tt.end)
+			if err != nil {
+				t.Fatalf("ParseTemplate(%q) failed: %v", tt.format, err)
+			}
+			if err := tmpl.Bind(nil); err != nil {
+				t.Fatalf("Bind() failed: %v", err)
+			}
+
+			var sb strings.Builder
+			if err := tmpl.Execute(&sb, fields, nil); err != nil {
+				t.Fatalf("Execute() failed: %v", err)
+			}
+			if got := sb.String(); got != tt.want {
+				t.Errorf("Execute() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTemplate_Invalid(t *testing.T) {
+	invalid := []string{
+		"{1",
+		"{}",
+		"{1|}",
+		"{1|upper|}",
+		"{1|shout}",
+		"{1|upper:2}",
+		"{1|trunc}",
+		"{1|trunc:x}",
+		"{1|trunc:-1}",
+		"{1|replace:a}",
+		`{1|default:"-}`,
+	}
+
+	for _, format := range invalid {
+		t.Run(format, func(t *testing.T) {
+			if _, err := ParseTemplate(format, "{", "}"); err == nil {
+				t.Errorf("ParseTemplate(%q) succeeded, want error", format)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"hello", 0, ""},
+		{"hello", 3, "hel"},
+		{"hello", 10, "hello"},
+		{"héllo", 2, "hé"},
+		{"👍🏽👍", 1, "👍🏽"},
+	}
+
+	for _, tt := range tests {
+		if got := truncate(tt.s, tt.n); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
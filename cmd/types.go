@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"math"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/spf13/pflag"
 )
@@ -29,3 +31,75 @@ func (i *limitValue) String() string {
 	return strconv.Itoa(i.Int())
 }
 func (i *limitValue) Int() int { return int(*i) }
+
+// escapedValue is value for string flags which accept backslash escapes
+type escapedValue string
+
+var _ pflag.Value = (*escapedValue)(nil)
+
+func (e *escapedValue) Set(s string) error {
+	*e = escapedValue(unescape(s))
+	return nil
+}
+
+func (e *escapedValue) Type() string { return "string" }
+func (e *escapedValue) String() string {
+	quoted := strconv.Quote(string(*e))
+	return strings.ReplaceAll(quoted[1:len(quoted)-1], `\"`, `"`)
+}
+
+// aggregateValue is value for the aggregation flags like "--sum", which append
+// their aggregate specs to a list in the order of the command line. The specs
+// of a flag without a function, like "--agg", are comma separated.
+type aggregateValue struct {
+	fn    string
+	specs *[]string
+}
+
+var _ pflag.Value = (*aggregateValue)(nil)
+
+func (a *aggregateValue) Set(s string) error {
+	if a.fn == "" {
+		*a.specs = append(*a.specs, strings.Split(s, ",")...)
+		return nil
+	}
+	*a.specs = append(*a.specs, a.fn+":"+s)
+	return nil
+}
+
+func (a *aggregateValue) Type() string {
+	if a.fn == "" {
+		return "strings"
+	}
+	return "stringArray"
+}
+func (a *aggregateValue) String() string { return "" }
+
+// countValue is value for "--count" flag, which also appends the count
+// aggregate to the list of aggregate specs
+type countValue struct {
+	count *bool
+	specs *[]string
+}
+
+var _ pflag.Value = (*countValue)(nil)
+
+func (c *countValue) Set(s string) error {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	switch {
+	case v && !*c.count:
+		*c.specs = append(*c.specs, "count")
+	case !v:
+		*c.specs = slices.DeleteFunc(*c.specs, func(spec string) bool {
+			return spec == "count"
+		})
+	}
+	*c.count = v
+	return nil
+}
+
+func (c *countValue) Type() string   { return "bool" }
+func (c *countValue) String() string { return strconv.FormatBool(*c.count) }
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// selectionKey returns a key which is equal for equal selections of fields
+func selectionKey(fields []string) string {
+	return strings.Join(fields, "\x00")
+}
+
+// Deduper reports whether a selection of fields was seen before, or right
+// before if only adjacent duplicates are detected like uniq(1)
+type Deduper struct {
+	adjacent bool
+	seen     map[string]struct{}
+	last     *string
+}
+
+// NewDeduper returns a Deduper which only detects adjacent duplicates if
+// adjacent is true
+func NewDeduper(adjacent bool) *Deduper {
+	return &Deduper{adjacent: adjacent, seen: map[string]struct{}{}}
+}
+
+// Seen reports whether fields is a duplicate and remembers it
+func (d *Deduper) Seen(fields []string) bool {
+	key := selectionKey(fields)
+	if d.adjacent {
+		dup := d.last != nil && *d.last == key
+		d.last = &key
+		return dup
+	}
+
+	if _, ok := d.seen[key]; ok {
+		return true
+	}
+	d.seen[key] = struct{}{}
+	return false
+}
+
+// counted is a distinct selection of fields and its number of occurrences
+type counted struct {
+	fields []string
+	n      int
+}
+
+// Counter counts the occurrences of each distinct selection of fields. The
+// selections are kept in the order they first appear.
+type Counter struct {
+	counts map[string]*counted
+	order  []*counted
+}
+
+// NewCounter returns an empty Counter
+func NewCounter() *Counter {
+	return &Counter{counts: map[string]*counted{}}
+}
+
+// Add counts an occurrence of fields
+func (c *Counter) Add(fields []string) {
+	key := selectionKey(fields)
+	if e, ok := c.counts[key]; ok {
+		e.n++
+		return
+	}
+	e := &counted{fields: slices.Clone(fields), n: 1}
+	c.counts[key] = e
+	c.order = append(c.order, e)
+}
+
+// Rows returns a row for each distinct selection prefixed with its number of
+// occurrences
+func (c *Counter) Rows() [][]string {
+	rows := make([][]string, len(c.order))
+	for i, e := range c.order {
+		rows[i] = append([]string{strconv.Itoa(e.n)}, e.fields...)
+	}
+	return rows
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDeduper(t *testing.T) {
+	input := [][]string{
+		{"root"}, {"root"}, {"nadim"}, {"root"}, {"nadim", "1"}, {}, {},
+	}
+
+	tests := []struct {
+		name     string
+		adjacent bool
+		want     []bool
+	}{
+		{"unique", false, []bool{false, true, false, true, false, false, true}},
+		{"adjacent", true, []bool{false, true, false, false, false, false, true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDeduper(tt.adjacent)
+			for i, fields := range input {
+				if got := d.Seen(fields); got != tt.want[i] {
+					t.Errorf("Seen(%q) = %v, want %v", fields, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCounter(t *testing.T) {
+	c := NewCounter()
+	input := [][]string{
+		{"root", "1"}, {"nadim", "2"}, {"root", "1"}, {"root"}, {"root", "1"},
+	}
+	for fields := range slices.Values(input) {
+		c.Add(fields)
+	}
+
+	want := [][]string{{"3", "root", "1"}, {"1", "nadim", "2"}, {"1", "root"}}
+	if got := c.Rows(); !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("Rows() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,478 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Filter is a predicate over the fields of a record parsed from an expression
+// like `{3} > 1024 && {USER} == "root"`. Fields are referenced by ranges in
+// braces and compared as numbers when both sides are numeric, otherwise as
+// strings. Supported operators, from the lowest precedence, are:
+//
+//	||                        logical or
+//	&&                        logical and
+//	!                         logical not
+//	== != < <= > >= =~ !~     comparison and regular expression matching
+//
+// Strings are quoted with double or single quotes, in which a backslash only
+// escapes the quote. Parentheses group expressions. A value on its own is true
+// when it is a non-zero number or a non-empty string.
+type Filter struct {
+	root   node
+	fields []*fieldNode
+}
+
+// ParseFilter parses the expression into a Filter. The column names used in
+// the field references are resolved by Bind.
+func ParseFilter(expr string) (*Filter, error) {
+	tokens, err := lexFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &filterParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in filter", p.tokens[p.pos].text)
+	}
+
+	return &Filter{root: root, fields: p.fields}, nil
+}
+
+// ParseMatches returns a Filter which holds when the fields selected by the
+// range of each spec, like "11=^/usr/bin/", match its regular expression. The
+// filter is inverted if invert is true.
+func ParseMatches(specs []string, invert bool) (*Filter, error) {
+	f := &Filter{root: &literalNode{v: boolValue(true)}}
+	for _, spec := range specs {
+		rangeStr, pattern, ok := strings.Cut(spec, "=")
+		if !ok || rangeStr == "" {
+			return nil, fmt.Errorf("invalid match, expected RANGE=REGEX: %q", spec)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern in match: %w", err)
+		}
+
+		field := &fieldNode{spec: rangeStr}
+		f.fields = append(f.fields, field)
+		f.root = &logicalNode{
+			and:   true,
+			left:  f.root,
+			right: &matchNode{left: field, re: re},
+		}
+	}
+
+	if invert {
+		f.root = &notNode{x: f.root}
+	}
+	return f, nil
+}
+
+// And returns a Filter which holds when both f and o hold. A nil Filter is
+// treated as always holding.
+func (f *Filter) And(o *Filter) *Filter {
+	if f == nil {
+		return o
+	}
+	if o == nil {
+		return f
+	}
+	return &Filter{
+		root:   &logicalNode{and: true, left: f.root, right: o.root},
+		fields: append(slices.Clip(f.fields), o.fields...),
+	}
+}
+
+// Bind resolves the field references of the filter with column names from
+// header, which may be nil.
+func (f *Filter) Bind(header []string) error {
+	for _, field := range f.fields {
+		r, err := parseRange(field.spec, header)
+		if err != nil {
+			return err
+		}
+		field.ranges = Ranges{r}
+	}
+	return nil
+}
+
+// Match reports whether the filter holds for the fields of a record
+func (f *Filter) Match(fields []string) bool {
+	return f.root.eval(fields).truthy()
+}
+
+// value is the result of evaluating a node
+type value struct {
+	str   string
+	num   float64
+	isNum bool
+}
+
+func stringValue(s string) value {
+	n, ok := parseDecimal(s)
+	return value{str: s, num: n, isNum: ok}
+}
+
+// parseDecimal parses s as a decimal number like "-1.5e3" surrounded by
+// optional white space. Unlike strconv.ParseFloat, it does not accept NaN,
+// infinities or hexadecimal numbers, which are usually just words in fields.
+func parseDecimal(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.Trim(s, "+-0123456789.eE") != "" {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	return n, err == nil
+}
+
+func boolValue(b bool) value {
+	if b {
+		return value{str: "1", num: 1, isNum: true}
+	}
+	return value{str: "0", num: 0, isNum: true}
+}
+
+func (v value) truthy() bool {
+	if v.isNum {
+		return v.num != 0
+	}
+	return v.str != ""
+}
+
+// compare returns -1, 0 or 1 comparing v to o numerically if both are numbers
+func (v value) compare(o value) int {
+	if v.isNum && o.isNum {
+		switch {
+		case v.num < o.num:
+			return -1
+		case v.num > o.num:
+			return 1
+		default:
+			return 0
+		}
+	}
+	return strings.Compare(v.str, o.str)
+}
+
+// node is a node of the syntax tree of a filter
+type node interface {
+	eval(fields []string) value
+}
+
+type fieldNode struct {
+	spec   string
+	ranges Ranges
+}
+
+func (n *fieldNode) eval(fields []string) value {
+	return stringValue(strings.Join(n.ranges.Select(fields), " "))
+}
+
+type literalNode struct{ v value }
+
+func (n *literalNode) eval([]string) value { return n.v }
+
+type notNode struct{ x node }
+
+func (n *notNode) eval(fields []string) value {
+	return boolValue(!n.x.eval(fields).truthy())
+}
+
+type logicalNode struct {
+	and         bool
+	left, right node
+}
+
+func (n *logicalNode) eval(fields []string) value {
+	left := n.left.eval(fields).truthy()
+	if n.and != left {
+		return boolValue(left)
+	}
+	return boolValue(n.right.eval(fields).truthy())
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n *compareNode) eval(fields []string) value {
+	c := n.left.eval(fields).compare(n.right.eval(fields))
+	switch n.op {
+	case "==":
+		return boolValue(c == 0)
+	case "!=":
+		return boolValue(c != 0)
+	case "<":
+		return boolValue(c < 0)
+	case "<=":
+		return boolValue(c <= 0)
+	case ">":
+		return boolValue(c > 0)
+	default: // ">="
+		return boolValue(c >= 0)
+	}
+}
+
+type matchNode struct {
+	negate bool
+	left   node
+	re     *regexp.Regexp
+}
+
+func (n *matchNode) eval(fields []string) value {
+	return boolValue(n.re.MatchString(n.left.eval(fields).str) != n.negate)
+}
+
+// filterParser is a recursive descent parser for filter expressions
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+	fields []*fieldNode
+}
+
+func (p *filterParser) peek() (filterToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return filterToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// accept consumes the next token if it is an operator in ops
+func (p *filterParser) accept(ops ...string) (string, bool) {
+	t, ok := p.peek()
+	if !ok || t.kind != tokenOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *filterParser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{left: left, right: right}
+	}
+}
+
+func (p *filterParser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{and: true, left: left, right: right}
+	}
+}
+
+func (p *filterParser) parseNot() (node, error) {
+	if _, ok := p.accept("!"); ok {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{x: x}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *filterParser) parseCompare() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	op, ok := p.accept("==", "!=", "<", "<=", ">", ">=", "=~", "!~")
+	if !ok {
+		return left, nil
+	}
+
+	if op == "=~" || op == "!~" {
+		t, ok := p.peek()
+		if !ok || t.kind != tokenString {
+			return nil, fmt.Errorf("%s requires a string pattern", op)
+		}
+		p.pos++
+		re, err := regexp.Compile(t.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern in filter: %w", err)
+		}
+		return &matchNode{negate: op == "!~", left: left, re: re}, nil
+	}
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return &compareNode{op: op, left: left, right: right}, nil
+}
+
+func (p *filterParser) parseOperand() (node, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, errors.New("unexpected end of filter")
+	}
+	p.pos++
+
+	switch t.kind {
+	case tokenField:
+		field := &fieldNode{spec: t.text}
+		p.fields = append(p.fields, field)
+		return field, nil
+	case tokenString:
+		return &literalNode{v: value{str: t.text}}, nil
+	case tokenNumber:
+		return &literalNode{v: stringValue(t.text)}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{v: boolValue(true)}, nil
+		case "false":
+			return &literalNode{v: boolValue(false)}, nil
+		}
+	case tokenOperator:
+		if t.text == "(" {
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.accept(")"); !ok {
+				return nil, errors.New("missing ')' in filter")
+			}
+			return x, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %q in filter", t.text)
+}
+
+type tokenKind int
+
+const (
+	tokenOperator tokenKind = iota
+	tokenField
+	tokenString
+	tokenNumber
+	tokenIdent
+)
+
+type filterToken struct {
+	kind tokenKind
+	text string
+}
+
+// filterOperators are the operators of the filter language, longest first
+var filterOperators = []string{
+	"&&", "||", "==", "!=", "<=", ">=", "=~", "!~",
+	"<", ">", "!", "(", ")",
+}
+
+// lexFilter splits a filter expression into tokens
+func lexFilter(expr string) ([]filterToken, error) {
+	tokens := make([]filterToken, 0, minResultSize)
+
+	for i := 0; i < len(expr); {
+		r, size := utf8.DecodeRuneInString(expr[i:])
+		rest := expr[i:]
+
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '{':
+			end := strings.IndexByte(rest, '}')
+			if end < 0 {
+				return nil, errors.New("missing '}' in filter")
+			}
+			tokens = append(tokens, filterToken{tokenField, rest[1:end]})
+			i += end + 1
+		case r == '"' || r == '\'':
+			end := 1
+			for end < len(rest) && rest[end] != byte(r) {
+				if rest[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(rest) {
+				return nil, errors.New("unterminated string in filter")
+			}
+			// only the quote is escaped to keep regular expressions readable
+			quote := string(r)
+			str := strings.ReplaceAll(rest[1:end], `\`+quote, quote)
+			tokens = append(tokens, filterToken{tokenString, str})
+			i += end + 1
+		case r == '-' || r == '.' || unicode.IsDigit(r):
+			end := 1
+			for end < len(rest) {
+				c := rest[end]
+				// a sign is only part of the number after an exponent
+				sign := (c == '+' || c == '-') && (rest[end-1] == 'e' || rest[end-1] == 'E')
+				if !sign && strings.IndexByte("0123456789.eE", c) < 0 {
+					break
+				}
+				end++
+			}
+			if _, err := strconv.ParseFloat(rest[:end], 64); err != nil {
+				return nil, fmt.Errorf("invalid number in filter: %q", rest[:end])
+			}
+			tokens = append(tokens, filterToken{tokenNumber, rest[:end]})
+			i += end
+		case unicode.IsLetter(r):
+			end := strings.IndexFunc(rest, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+			})
+			if end < 0 {
+				end = len(rest)
+			}
+			tokens = append(tokens, filterToken{tokenIdent, rest[:end]})
+			i += end
+		default:
+			op := ""
+			for _, o := range filterOperators {
+				if strings.HasPrefix(rest, o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q in filter", r)
+			}
+			tokens = append(tokens, filterToken{tokenOperator, op})
+			i += len(op)
+		}
+	}
+
+	return tokens, nil
+}
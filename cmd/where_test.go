@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"testing"
+	"unicode"
+)
+
+func TestFilter_Match(t *testing.T) {
+	header := []string{"USER", "PID", "%CPU", "COMMAND"}
+	root := []string{
+		"root", "1", "0.5", "/sbin/init splash", "NaN", "inf", "0x10", " -1.5e3",
+	}
+	user := []string{"nadim", "2048", "12.0", "/usr/bin/fish"}
+
+	tests := []struct {
+		expr string
+		want [2]bool
+	}{
+		{`{1} == "root"`, [2]bool{true, false}},
+		{`{USER} != "root"`, [2]bool{false, true}},
+		{`{2} > 1024`, [2]bool{false, true}},
+		{`{PID} <= 1`, [2]bool{true, false}},
+		{`{%CPU} >= 0.5 && {%CPU} < 10`, [2]bool{true, false}},
+		{`{1} == "root" || {2} == 2048`, [2]bool{true, true}},
+		{`!({1} == "root")`, [2]bool{false, true}},
+		{`{COMMAND} =~ "^/usr/bin/"`, [2]bool{false, true}},
+		{`{4} !~ '\s'`, [2]bool{false, true}},
+		{`{4} == "/sbin/init splash"`, [2]bool{true, false}},
+		{`{2:3} == "1 0.5"`, [2]bool{true, false}},
+		{`{10}`, [2]bool{false, false}},
+		{`{2} == 1 && true`, [2]bool{true, false}},
+		{`false || {1}`, [2]bool{true, true}},
+		{`"10" < "9"`, [2]bool{true, true}},
+		{`{1} == 'say \'hi\''`, [2]bool{false, false}},
+		{`{5} == 0`, [2]bool{false, false}},
+		{`{5} == "NaN"`, [2]bool{true, false}},
+		{`{6} == "Infinity"`, [2]bool{false, false}},
+		{`{6} == "inf"`, [2]bool{true, false}},
+		{`{7} == 16`, [2]bool{false, false}},
+		{`{8} == -1500`, [2]bool{true, false}},
+		{`{3} > 1e-1 && {8} == -1.5e+3`, [2]bool{true, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := ParseFilter(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseFilter(%q) failed: %v", tt.expr, err)
+			}
+			if err := f.Bind(header); err != nil {
+				t.Fatalf("Bind() failed: %v", err)
+			}
+
+			for i, fields := range [][]string{root, user} {
+				if got := f.Match(fields); got != tt.want[i] {
+					t.Errorf("Match(%q) = %v, want %v", fields, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseFilter_Invalid(t *testing.T) {
+	invalid := []string{
+		``,
+		`{1} ==`,
+		`{1 == 2`,
+		`({1} == 2`,
+		`{1} == 2)`,
+		`{1} =~ {2}`,
+		`{1} =~ "("`,
+		`"unterminated`,
+		`{1} = 2`,
+		`unknown`,
+		`{1} == 1e+`,
+	}
+
+	for _, expr := range invalid {
+		if _, err := ParseFilter(expr); err == nil {
+			t.Errorf("ParseFilter(%q) succeeded, want error", expr)
+		}
+	}
+
+	f, err := ParseFilter(`{NAME} == 1`)
+	if err != nil {
+		t.Fatalf("ParseFilter() failed: %v", err)
+	}
+	if err := f.Bind([]string{"USER"}); err == nil {
+		t.Errorf("Bind() with unknown column succeeded, want error")
+	}
+}
+
+func TestParseMatches(t *testing.T) {
+	header := []string{"USER", "PID", "COMMAND"}
+	records := [][]string{
+		{"root", "1", "/sbin/init"},
+		{"nadim", "20", "/usr/bin/fish"},
+		{"root", "300", "/usr/bin/sshd"},
+	}
+
+	tests := []struct {
+		name   string
+		specs  []string
+		invert bool
+		want   []bool
+	}{
+		{"single match", []string{"3=^/usr/bin/"}, false, []bool{false, true, true}},
+		{"column name", []string{"USER=root"}, false, []bool{true, false, true}},
+		{"all must match", []string{"1=root", "3=^/usr"}, false, []bool{false, false, true}},
+		{"inverted", []string{"3=^/usr/bin/"}, true, []bool{true, false, false}},
+		{"pattern with equals", []string{"3=a=b"}, false, []bool{false, false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseMatches(tt.specs, tt.invert)
+			if err != nil {
+				t.Fatalf("ParseMatches(%q) failed: %v", tt.specs, err)
+			}
+			if err := f.Bind(header); err != nil {
+				t.Fatalf("Bind() failed: %v", err)
+			}
+
+			for i, fields := range records {
+				if got := f.Match(fields); got != tt.want[i] {
+					t.Errorf("Match(%q) = %v, want %v", fields, got, tt.want[i])
+				}
+			}
+		})
+	}
+
+	for _, spec := range []string{"root", "=root", "1=("} {
+		if _, err := ParseMatches([]string{spec}, false); err == nil {
+			t.Errorf("ParseMatches(%q) succeeded, want error", spec)
+		}
+	}
+}
+
+func TestFilter_And(t *testing.T) {
+	where, err := ParseFilter(`{2} > 10`)
+	if err != nil {
+		t.Fatalf("ParseFilter() failed: %v", err)
+	}
+	match, err := ParseMatches([]string{"1=root"}, false)
+	if err != nil {
+		t.Fatalf("ParseMatches() failed: %v", err)
+	}
+
+	f := (*Filter)(nil).And(where).And(match)
+	if err := f.Bind(nil); err != nil {
+		t.Fatalf("Bind() failed: %v", err)
+	}
+
+	records := map[string]bool{
+		"root 1":   false,
+		"root 20":  true,
+		"nadim 20": false,
+	}
+	for record, want := range records {
+		fields := FieldNFunc([]byte(record), unicode.IsSpace, -1)
+		if got := f.Match(fields); got != want {
+			t.Errorf("Match(%q) = %v, want %v", fields, got, want)
+		}
+	}
+}